@@ -3,6 +3,9 @@ module github.com/renatoathaydes/magnanimous
 go 1.15
 
 require (
+	github.com/BurntSushi/toml v1.2.1
 	github.com/Depado/bfchroma/v2 v2.0.0
+	github.com/alecthomas/chroma/v2 v2.2.0
 	github.com/russross/blackfriday/v2 v2.1.0
+	gopkg.in/yaml.v3 v3.0.1
 )
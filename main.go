@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/renatoathaydes/magnanimous/mg"
@@ -16,16 +17,74 @@ const (
 	TargetDir = "target/"
 )
 
+type options struct {
+	rootDir     string
+	globalCtx   string
+	profile     string
+	definitions definitions
+	drafts      bool
+	future      bool
+	sitemap     bool
+	feeds       feeds
+	gitDates    bool
+	codeStyles  []string
+	checkLinks  bool
+	sandbox     bool
+}
+
+// definitions is a repeatable flag of the form name=value.
+type definitions map[string]string
+
+func (d definitions) String() string {
+	return fmt.Sprint(map[string]string(d))
+}
+
+func (d definitions) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return fmt.Errorf("expected name=value, got '%s'", value)
+	}
+	d[strings.TrimSpace(parts[0])] = parts[1]
+	return nil
+}
+
+// feeds is a repeatable flag of the form dir[:rss|:atom].
+type feeds []mg.Feed
+
+func (f *feeds) String() string {
+	return fmt.Sprint([]mg.Feed(*f))
+}
+
+func (f *feeds) Set(value string) error {
+	feed, err := mg.ParseFeed(value)
+	if err == nil {
+		*f = append(*f, feed)
+	}
+	return err
+}
+
 func main() {
 	start := time.Now()
 
-	rootDir, globalCtx, ok := parseOptions()
+	opts, ok := parseOptions()
 
 	if !ok {
 		return
 	}
 
-	mag := mg.Magnanimous{SourcesDir: filepath.Join(*rootDir, SourceDir), GlobalContex: *globalCtx}
+	mag := mg.Magnanimous{
+		SourcesDir:      filepath.Join(opts.rootDir, SourceDir),
+		GlobalContex:    opts.globalCtx,
+		Profile:         opts.profile,
+		Definitions:     opts.definitions,
+		IncludeDrafts:   opts.drafts,
+		IncludeFuture:   opts.future,
+		Sitemap:         opts.sitemap,
+		Feeds:           opts.feeds,
+		GitDates:        opts.gitDates,
+		CodeStylesheets: opts.codeStyles,
+		Sandbox:         opts.sandbox,
+	}
 	webFiles, err := mag.ReadAll()
 	if err != nil {
 		log.Printf("ERROR: %s", err)
@@ -37,20 +96,61 @@ func main() {
 		return
 	}
 
-	err = mag.WriteTo(filepath.Join(*rootDir, TargetDir), webFiles)
+	targetDir := filepath.Join(opts.rootDir, TargetDir)
+	err = mag.WriteTo(targetDir, webFiles)
 	if err != nil {
 		log.Printf("ERROR: %s", err)
 		panic(err)
 	}
 
+	if opts.checkLinks {
+		err = mag.CheckLinks(mg.DirOutput(targetDir), webFiles)
+		if err != nil {
+			log.Printf("ERROR: %s", err)
+			panic(err)
+		}
+	}
+
 	log.Printf("Magnanimous generated website in %s\n", time.Since(start))
 }
 
-func parseOptions() (rootDir, globalContext *string, ok bool) {
-	globalContext = flag.String("globalctx", "",
+func parseOptions() (opts options, ok bool) {
+	globalContext := flag.String("globalctx", "",
 		"Path to the global context file relative to the 'processed' directory.")
+	profile := flag.String("profile", "",
+		"Name of the build profile. The profile's global context file (<globalctx>.<profile>) is layered "+
+			"on top of the global context file.")
+	markdown := flag.String("markdown", "",
+		"Markdown extensions to enable, or to disable if prefixed with '-', as in 'footnotes,-smartypants' "+
+			"(sets the '"+mg.MarkdownContextName+"' global context variable).")
 	style := flag.String("style", "lovelace",
 		"Style name for code highlighting. See https://xyproto.github.io/splash/docs/all.html.")
+	opts.definitions = make(definitions)
+	flag.Var(opts.definitions, "define",
+		"Define a global context variable, as in name=value (overrides the global context file). May be repeated.")
+
+	flag.BoolVar(&opts.drafts, "drafts", false, "Publish files marked as drafts.")
+	flag.BoolVar(&opts.future, "future", false, "Publish files whose publish date is in the future.")
+	flag.BoolVar(&opts.sitemap, "sitemap", false,
+		"Write a sitemap.xml file (full URLs require the 'siteURL' variable in the global context).")
+
+	styleClasses := flag.Bool("style-classes", false,
+		"Highlight code using CSS classes instead of inline styles (requires a code stylesheet, see -style-css).")
+	styleCSS := flag.String("style-css", "",
+		"Comma-separated names of code styles whose stylesheets (chroma-<style>.css) are written to the "+
+			"target directory. Implies -style-classes. Styles after the first only apply within elements with "+
+			"the class chroma-<style>.")
+	flag.Var(&opts.feeds, "feed",
+		"Write RSS and Atom feeds for a directory relative to the 'processed' directory, as in /posts. "+
+			"Append :rss or :atom to write only one of them. May be repeated.")
+
+	flag.BoolVar(&opts.gitDates, "git-dates", false,
+		"Use the git history to find when files were last updated, and by whom.")
+	flag.BoolVar(&opts.sandbox, "sandbox", false,
+		"Reject paths and symbolic links leading outside of the source directory, and hide environment "+
+			"variables (for untrusted sources).")
+	flag.BoolVar(&opts.checkLinks, "check-links", false,
+		"After generating the website, check that internal links and #anchors in HTML files are not broken.")
 
 	help := flag.Bool("help", false, "Print usage help.")
 
@@ -63,26 +163,35 @@ func parseOptions() (rootDir, globalContext *string, ok bool) {
 	flag.Parse()
 
 	mg.SetCodeStyle(*style)
+	if *styleCSS != "" {
+		for _, s := range strings.Split(*styleCSS, ",") {
+			opts.codeStyles = append(opts.codeStyles, strings.TrimSpace(s))
+		}
+	}
+	mg.SetCodeClasses(*styleClasses || len(opts.codeStyles) > 0)
 
 	otherArgs := flag.Args()
 
 	if *help {
 		flag.Usage()
-		return nil, nil, false
+		return opts, false
 	}
 
-	var rootDirValue string
 	switch len(otherArgs) {
 	case 0:
-		rootDirValue = ""
+		opts.rootDir = ""
 	case 1:
-		rootDirValue = otherArgs[0]
+		opts.rootDir = otherArgs[0]
 	default:
 		log.Printf("ERROR: too many arguments provided")
 		flag.Usage()
-		return nil, nil, false
+		return opts, false
+	}
+	opts.globalCtx = *globalContext
+	if *markdown != "" {
+		opts.definitions[mg.MarkdownContextName] = *markdown
 	}
-	rootDir = &rootDirValue
+	opts.profile = *profile
 
 	ok = true
 
@@ -0,0 +1,128 @@
+package mg
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/russross/blackfriday/v2"
+)
+
+var codeBlockAttributeRegex = regexp.MustCompile(`([a-zA-Z_]+)\s*=\s*(?:"([^"]*)"|([^\s,"]+))`)
+
+// renderCodeBlock highlights a code block with chroma, supporting per-code-block options given as attributes
+// after the language, as in:
+//
+//	```go {linenos=true hl_lines="3-5 8" linenostart=10 title="main.go"}
+//
+// The supported attributes are:
+//   - linenos: true to show line numbers, or table to show them in a separate table column.
+//   - linenostart: the number of the first line.
+//   - hl_lines: the lines to highlight, as line numbers and ranges separated by spaces or commas.
+//   - title: a title, shown as the caption of the code block.
+func (r *markdownRenderer) renderCodeBlock(w io.Writer, node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+	opts := parseCodeBlockInfo(string(node.Info))
+	if opts.title != "" {
+		fmt.Fprintf(w, "<figure class=\"code\">\n<figcaption>%s</figcaption>\n", html.EscapeString(opts.title))
+	}
+	lexer := lexers.Get(opts.language)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	iterator, err := lexer.Tokenise(nil, string(node.Literal))
+	if err == nil {
+		formatter := chromahtml.New(append(r.ChromaOptions, opts.options...)...)
+		err = formatter.Format(w, r.Style, iterator)
+	}
+	if err != nil {
+		r.Base.RenderNode(w, node, entering)
+	}
+	if opts.title != "" {
+		fmt.Fprint(w, "</figure>\n")
+	}
+	return blackfriday.SkipChildren
+}
+
+// codeBlockOptions are the options of a single code block.
+type codeBlockOptions struct {
+	language string
+	title    string
+	options  []chromahtml.Option
+}
+
+// parseCodeBlockInfo parses the info string of a code block, e.g. go {linenos=true}.
+func parseCodeBlockInfo(info string) codeBlockOptions {
+	var result codeBlockOptions
+	info = strings.TrimSpace(info)
+	attributes := ""
+	if i := strings.Index(info, "{"); i >= 0 {
+		result.language = strings.TrimSpace(info[:i])
+		attributes = strings.TrimSuffix(info[i+1:], "}")
+	} else if strings.Contains(info, "=") {
+		// blackfriday removes the braces when there's no language
+		attributes = info
+	} else {
+		result.language = info
+	}
+	for _, m := range codeBlockAttributeRegex.FindAllStringSubmatch(attributes, -1) {
+		name, value := m[1], m[2]+m[3]
+		switch name {
+		case "linenos":
+			switch value {
+			case "true", "inline":
+				result.options = append(result.options, chromahtml.WithLineNumbers(true))
+			case "table":
+				result.options = append(result.options, chromahtml.WithLineNumbers(true),
+					chromahtml.LineNumbersInTable(true))
+			case "false":
+			default:
+				log.Printf("WARNING: invalid code block linenos value (expected true, false or table): %s", value)
+			}
+		case "linenostart":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				log.Printf("WARNING: invalid code block linenostart value: %s", value)
+				continue
+			}
+			result.options = append(result.options, chromahtml.BaseLineNumber(n))
+		case "hl_lines":
+			ranges, err := parseLineRanges(value)
+			if err != nil {
+				log.Printf("WARNING: invalid code block hl_lines value: %s", err)
+				continue
+			}
+			result.options = append(result.options, chromahtml.HighlightLines(ranges))
+		case "title":
+			result.title = value
+		default:
+			log.Printf("WARNING: ignoring unknown code block attribute: %s", name)
+		}
+	}
+	return result
+}
+
+// parseLineRanges parses line numbers and ranges, such as "1 3-5", into chroma line ranges.
+func parseLineRanges(value string) ([][2]int, error) {
+	var ranges [][2]int
+	for _, part := range strings.FieldsFunc(value, func(r rune) bool { return r == ' ' || r == ',' }) {
+		bounds := strings.SplitN(part, "-", 2)
+		start, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("not a line number or range: %s", part)
+		}
+		end := start
+		if len(bounds) == 2 {
+			if end, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, fmt.Errorf("not a line number or range: %s", part)
+			}
+		}
+		ranges = append(ranges, [2]int{start, end})
+	}
+	return ranges, nil
+}
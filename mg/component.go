@@ -1,7 +1,13 @@
 package mg
 
 import (
+	"fmt"
 	"io"
+	"log"
+	"strings"
+	"unicode"
+
+	"github.com/renatoathaydes/magnanimous/mg/expression"
 )
 
 type Component struct {
@@ -9,9 +15,17 @@ type Component struct {
 	Location *Location
 	Text     string
 	resolver FileResolver
+	args     []componentArg
 	contents []Content
 }
 
+// componentArg is a named argument given to a component, as in {{ component _note.html (kind="warning") }}.
+type componentArg struct {
+	name string
+	text string
+	expr *expression.Expression
+}
+
 var _ Content = (*Component)(nil)
 var _ ContentContainer = (*Component)(nil)
 var _ Inclusion = (*Component)(nil)
@@ -25,11 +39,18 @@ type internalComponent struct {
 var _ Content = (*internalComponent)(nil)
 
 func NewComponentInstruction(arg string, location *Location, original string, resolver FileResolver) Content {
+	path, argsText := splitComponentArgs(strings.TrimSpace(arg))
+	args, err := parseComponentArgs(argsText)
+	if err != nil {
+		log.Printf("WARNING: (%s) malformed component arguments: %s", location.String(), err.Error())
+		return unevaluatedExpression(original, location)
+	}
 	return &Component{
-		Path:     arg,
+		Path:     path,
 		Location: location,
 		Text:     original,
 		resolver: resolver,
+		args:     args,
 	}
 }
 
@@ -55,11 +76,120 @@ func (c *Component) Write(writer io.Writer, context Context) ([]Content, error)
 		return nil, err
 	}
 
+	contents := componentFile.Processed.GetContents()
+	params := componentParams(contents)
+
+	for _, arg := range c.args {
+		v, err := expression.EvalExpr(arg.expr, context)
+		if err != nil {
+			return nil, NewError(*c.Location, ParseError,
+				fmt.Sprintf("cannot evaluate component argument '%s': %s", arg.text, err.Error()))
+		}
+		context.Set(arg.name, v)
+	}
+
 	resolveContext(c.contents, context)
 
+	err = bindParams(params, c.Path, c.Location, context)
+	if err != nil {
+		return nil, err
+	}
+
+	// missing parameters are reported before unknown arguments, which are often misspelled parameters
+	for _, arg := range c.args {
+		if len(params) > 0 && findParam(params, arg.name) == nil {
+			return nil, NewError(*c.Location, ParseError,
+				fmt.Sprintf("component %s has no parameter '%s'", c.Path, arg.name))
+		}
+	}
+
 	context.Set("__contents__", &internalComponent{location: c.Location, contents: c.contents})
 
-	return componentFile.Processed.GetContents(), nil
+	return contents, nil
+}
+
+// splitComponentArgs splits the argument of a component instruction into the component path and the text
+// of its arguments, which are given between parenthesis after the path.
+func splitComponentArgs(arg string) (path, args string) {
+	if !strings.HasSuffix(arg, ")") {
+		return arg, ""
+	}
+	var quote rune
+	for i, r := range arg {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '`':
+			quote = r
+		case r == '(' && i > 0 && unicode.IsSpace(rune(arg[i-1])):
+			return strings.TrimSpace(arg[:i]), arg[i+1 : len(arg)-1]
+		}
+	}
+	return arg, ""
+}
+
+// parseComponentArgs parses component arguments of the form name=expression, separated by commas or spaces.
+//
+// Expressions containing spaces must be quoted or enclosed in parenthesis.
+func parseComponentArgs(text string) ([]componentArg, error) {
+	var args []componentArg
+	isSeparator := func(r rune) bool { return r == ',' || unicode.IsSpace(r) }
+	runes := []rune(text)
+	i := 0
+	for {
+		for i < len(runes) && isSeparator(runes[i]) {
+			i++
+		}
+		if i == len(runes) {
+			return args, nil
+		}
+		start := i
+		for i < len(runes) && (runes[i] == '_' || unicode.IsLetter(runes[i]) || (i > start && unicode.IsDigit(runes[i]))) {
+			i++
+		}
+		name := string(runes[start:i])
+		for i < len(runes) && unicode.IsSpace(runes[i]) {
+			i++
+		}
+		if name == "" || i == len(runes) || runes[i] != '=' {
+			return nil, fmt.Errorf("expected name=value at '%s'", string(runes[start:]))
+		}
+		i++
+		for i < len(runes) && unicode.IsSpace(runes[i]) {
+			i++
+		}
+		valueStart := i
+		var quote rune
+		depth := 0
+		for ; i < len(runes) && (quote != 0 || depth > 0 || !isSeparator(runes[i])); i++ {
+			r := runes[i]
+			switch {
+			case quote != 0:
+				if r == '\\' && quote == '"' {
+					i++
+				} else if r == quote {
+					quote = 0
+				}
+			case r == '"' || r == '`':
+				quote = r
+			case r == '(' || r == '[' || r == '{':
+				depth++
+			case r == ')' || r == ']' || r == '}':
+				depth--
+			}
+		}
+		value := string(runes[valueStart:i])
+		if value == "" {
+			return nil, fmt.Errorf("missing value of argument '%s'", name)
+		}
+		expr, err := expression.ParseExpr(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of argument '%s': %s (%s)", name, value, err.Error())
+		}
+		args = append(args, componentArg{name: name, text: string(runes[start:i]), expr: &expr})
+	}
 }
 
 func (c *internalComponent) GetLocation() *Location {
@@ -97,7 +97,6 @@ func (c *ContextStack) Get(name string) (interface{}, bool) {
 		}
 	}
 	return nil, false
-	return c.Top().Get(name)
 }
 
 // Set the value for the given name.
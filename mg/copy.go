@@ -3,49 +3,52 @@ package mg
 import (
 	"bufio"
 	"io"
-	"os"
+	"io/fs"
 	"path/filepath"
 )
 
 // CopyAll copies all given files under the basePath by putting the files into the provided filesMap,
 // then writing them out when the resulting ProcessedFiles are written.
 func CopyAll(files *[]string, basePath string, filesMap WebFilesMap) error {
-	for _, file := range *files {
-		wf, err := Copy(file, basePath, true)
-		if err != nil {
-			return err
-		}
-		wf.SkipIfUpToDate = true // all static files should not be written when older than destination
-		filesMap.WebFiles[file] = *wf
-	}
-	return nil
+	return copyAll(osFS{}, *files, basePath, filesMap, true)
 }
 
 func AddNonWritables(files *[]string, basePath string, filesMap WebFilesMap) error {
-	for _, file := range *files {
-		wf, err := Copy(file, basePath, false)
+	return copyAll(osFS{}, *files, basePath, filesMap, false)
+}
+
+func copyAll(fsys fs.FS, files []string, basePath string, filesMap WebFilesMap, writable bool) error {
+	for _, file := range files {
+		wf, err := copyFile(fsys, file, basePath, writable)
 		if err != nil {
 			return err
 		}
+		// all static files should not be written when older than destination
+		wf.SkipIfUpToDate = writable
 		filesMap.WebFiles[file] = *wf
 	}
 	return nil
 }
 
 func Copy(file, basePath string, writable bool) (*WebFile, error) {
-	stats, err := os.Stat(file)
+	return copyFile(osFS{}, file, basePath, writable)
+}
+
+func copyFile(fsys fs.FS, file, basePath string, writable bool) (*WebFile, error) {
+	stats, err := fs.Stat(fsys, filepath.ToSlash(file))
 	if err != nil {
 		return nil, &MagnanimousError{Code: IOError, message: err.Error()}
 	}
 
-	var proc = ProcessedFile{Path: file, LastUpdated: stats.ModTime()}
-	proc.AppendContent(&copiedContent{file: file})
+	var proc = ProcessedFile{Path: file, LastUpdated: stats.ModTime(), source: fsys}
+	proc.AppendContent(&copiedContent{file: file, source: fsys})
 	return &WebFile{BasePath: basePath, Name: filepath.Base(file), Processed: &proc, NonWritable: !writable}, nil
 }
 
 type copiedContent struct {
 	UnscopedContent
-	file string
+	file   string
+	source fs.FS
 }
 
 var _ Content = (*copiedContent)(nil)
@@ -56,7 +59,7 @@ func (c *copiedContent) GetLocation() *Location {
 }
 
 func (c *copiedContent) Write(writer io.Writer, context Context) ([]Content, error) {
-	source, err := os.Open(c.file)
+	source, err := sourceFS(c.source).Open(filepath.ToSlash(c.file))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,136 @@
+package mg
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// DataContextName is the name of the variable holding the contents of the data directory.
+const DataContextName = "data"
+
+type dataParser func(contents []byte) (interface{}, error)
+
+var dataParsers = map[string]dataParser{
+	".json": parseJSONData,
+	".yaml": parseYAMLData,
+	".yml":  parseYAMLData,
+	".toml": parseTOMLData,
+	".csv":  parseCSVData,
+}
+
+// loadData parses all data files found in the data directory of the given files map.
+//
+// Each file's contents are put into the returned map under its path relative to the data directory,
+// without extension, so that data/team/members.yaml is available as data.team.members.
+func loadData(fsys fs.FS, dataDir string, filesMap WebFilesMap) (map[string]interface{}, error) {
+	data := make(map[string]interface{})
+	var files []string
+	for file := range filesMap.WebFiles {
+		if strings.HasPrefix(file, dataDir+string(filepath.Separator)) {
+			files = append(files, file)
+		}
+	}
+	// sort so that errors and warnings are reported in a stable order
+	sort.Strings(files)
+
+	for _, file := range files {
+		parser, ok := dataParsers[strings.ToLower(filepath.Ext(file))]
+		if !ok {
+			log.Printf("WARNING: ignoring data file with unsupported extension: %s", file)
+			continue
+		}
+		contents, err := fs.ReadFile(fsys, filepath.ToSlash(file))
+		if err != nil {
+			return nil, &MagnanimousError{Code: IOError, message: err.Error()}
+		}
+		value, err := parser(contents)
+		if err != nil {
+			return nil, NewError(Location{Origin: file}, ParseError,
+				fmt.Sprintf("invalid data file: %s", err.Error()))
+		}
+		rel, err := filepath.Rel(dataDir, file)
+		if err != nil {
+			return nil, &MagnanimousError{Code: IOError, message: err.Error()}
+		}
+		rel = rel[0 : len(rel)-len(filepath.Ext(rel))]
+		putData(data, strings.Split(filepath.ToSlash(rel), "/"), normalizeValue(value), file)
+	}
+	return data, nil
+}
+
+func putData(data map[string]interface{}, keys []string, value interface{}, file string) {
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := data[key].(map[string]interface{})
+		if !ok {
+			if _, exists := data[key]; exists {
+				log.Printf("WARNING: data file %s overrides existing data value: %s", file, key)
+			}
+			next = make(map[string]interface{})
+			data[key] = next
+		}
+		data = next
+	}
+	key := keys[len(keys)-1]
+	if existing, ok := data[key].(map[string]interface{}); ok {
+		if m, ok := value.(map[string]interface{}); ok {
+			// a directory and a file with the same name: merge their values
+			for k, v := range m {
+				existing[k] = v
+			}
+			return
+		}
+		log.Printf("WARNING: data file %s overrides existing data value: %s", file, key)
+	}
+	data[key] = value
+}
+
+func parseJSONData(contents []byte) (interface{}, error) {
+	var value interface{}
+	err := json.Unmarshal(contents, &value)
+	return value, err
+}
+
+func parseYAMLData(contents []byte) (interface{}, error) {
+	var value interface{}
+	err := yaml.Unmarshal(contents, &value)
+	return value, err
+}
+
+func parseTOMLData(contents []byte) (interface{}, error) {
+	value := make(map[string]interface{})
+	_, err := toml.Decode(string(contents), &value)
+	return value, err
+}
+
+// parseCSVData parses CSV contents into a list of rows, each row mapping the header names to the row values.
+func parseCSVData(contents []byte) (interface{}, error) {
+	records, err := csv.NewReader(bytes.NewReader(contents)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]interface{}, 0, len(records))
+	if len(records) == 0 {
+		return rows, nil
+	}
+	header := records[0]
+	for _, record := range records[1:] {
+		row := make(map[string]interface{}, len(header))
+		for i, name := range header {
+			if i < len(record) {
+				row[name] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -0,0 +1,120 @@
+package mg
+
+import (
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/renatoathaydes/magnanimous/mg/expression"
+)
+
+// escaping is implemented by Contents whose evaluated values are escaped when written.
+type escaping interface {
+	setEscape(escape func(string) string)
+}
+
+var _ escaping = (*EvalContent)(nil)
+var _ escaping = (*SlotOrContent)(nil)
+
+// isHtml returns whether a file is an HTML file, whose evaluated values are escaped automatically.
+func isHtml(file string) bool {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".html", ".htm":
+		return true
+	}
+	return false
+}
+
+// urlAttributes are the attributes whose values are URLs, which are checked for unsafe schemes.
+var urlAttributes = map[string]bool{
+	"href": true, "src": true, "action": true, "formaction": true, "poster": true, "cite": true,
+}
+
+// safeURLSchemes are the URL schemes allowed in values written to URL attributes.
+var safeURLSchemes = map[string]bool{"http": true, "https": true, "mailto": true, "tel": true}
+
+// unsafeURL replaces URLs with an unsafe scheme, such as javascript:, written to URL attributes.
+const unsafeURL = "#unsafe-url"
+
+// htmlEscapeAt returns the function to escape values written after the given HTML text (in lower-case),
+// depending on whether the text ends within a script, a style, an attribute value or elsewhere.
+func htmlEscapeAt(text string) func(string) string {
+	if withinElement(text, "script") {
+		return template.JSEscapeString
+	}
+	if withinElement(text, "style") {
+		return expression.EscapeCSS
+	}
+	tagStart := strings.LastIndex(text, "<")
+	if tagStart >= 0 && tagStart > strings.LastIndex(text, ">") {
+		var quote byte
+		quoteStart := -1
+		for i := tagStart; i < len(text); i++ {
+			if c := text[i]; quote != 0 {
+				if c == quote {
+					quote = 0
+				}
+			} else if c == '"' || c == '\'' {
+				quote, quoteStart = c, i
+			}
+		}
+		if quote != 0 {
+			if urlAttributes[attributeNameBefore(text[:quoteStart])] {
+				return urlEscape(text[quoteStart+1:], expression.EscapeHTML)
+			}
+		} else if strings.HasSuffix(text, "=") {
+			if urlAttributes[attributeNameBefore(text)] {
+				return urlEscape("", expression.EscapeAttr)
+			}
+			return expression.EscapeAttr
+		}
+	}
+	return expression.EscapeHTML
+}
+
+// withinElement returns whether the given HTML text ends within the contents of an element with the given name.
+func withinElement(text, name string) bool {
+	i := strings.LastIndex(text, "<"+name)
+	return i >= 0 && i > strings.LastIndex(text, "</"+name) && strings.Contains(text[i:], ">")
+}
+
+// attributeNameBefore returns the name of the attribute whose value starts after the given text, which ends
+// with '=' (possibly followed by spaces).
+func attributeNameBefore(text string) string {
+	text = strings.TrimRightFunc(text, unicode.IsSpace)
+	if !strings.HasSuffix(text, "=") {
+		return ""
+	}
+	text = strings.TrimRightFunc(strings.TrimSuffix(text, "="), unicode.IsSpace)
+	return text[strings.LastIndexFunc(text, unicode.IsSpace)+1:]
+}
+
+// urlEscape returns a function escaping values written to a URL attribute after the given prefix of its value.
+//
+// If the prefix does not determine the URL scheme yet, values resulting in an unsafe URL scheme are replaced
+// with [unsafeURL].
+func urlEscape(prefix string, escape func(string) string) func(string) string {
+	return func(value string) string {
+		if !strings.ContainsAny(prefix, ":/?#") && !isSafeURL(prefix+value) {
+			return unsafeURL
+		}
+		return escape(value)
+	}
+}
+
+// isSafeURL returns whether a URL is relative or has one of the [safeURLSchemes].
+func isSafeURL(url string) bool {
+	i := strings.IndexAny(url, ":/?#")
+	if i < 0 || url[i] != ':' {
+		return true
+	}
+	// browsers ignore spaces and control characters within the scheme, as in "java\tscript:"
+	scheme := strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, url[:i])
+	return safeURLSchemes[scheme]
+}
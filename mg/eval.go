@@ -15,6 +15,7 @@ type EvalContent struct {
 	Text     string
 	Location *Location
 	resolver FileResolver
+	escape   func(string) string
 }
 
 var _ Content = (*EvalContent)(nil)
@@ -39,6 +40,14 @@ func (e *EvalContent) Write(writer io.Writer, context Context) ([]Content, error
 		case Content:
 			return []Content{c}, nil
 		case string:
+			if e.escape != nil {
+				c = e.escape(c)
+			}
+			if _, err := writer.Write([]byte(c)); err != nil {
+				return nil, err
+			}
+			return nil, nil
+		case expression.RawString:
 			if _, err := writer.Write([]byte(c)); err != nil {
 				return nil, err
 			}
@@ -52,7 +61,7 @@ func (e *EvalContent) Write(writer io.Writer, context Context) ([]Content, error
 	return unevaluatedExpressions(e.Text, e.Location), nil
 }
 
-// eval evaluates the given expression to either a string or a Content, or an error.
+// eval evaluates the given expression to either a string, a raw string or a Content, or an error.
 func (e *EvalContent) eval(context Context) (interface{}, error) {
 	r, err := expression.EvalExpr(e.Expr, context)
 	if err == nil {
@@ -60,6 +69,9 @@ func (e *EvalContent) eval(context Context) (interface{}, error) {
 		if c, ok := r.(Content); ok {
 			return c, nil
 		}
+		if raw, ok := r.(expression.RawString); ok {
+			return raw, nil
+		}
 		// evaluate special types to a simple string to write
 		return e.evalSpecialType(r, context)
 	}
@@ -106,6 +118,10 @@ func (e *EvalContent) toInclusion(p *expression.Path) Inclusion {
 	return &inc
 }
 
+func (e *EvalContent) setEscape(escape func(string) string) {
+	e.escape = escape
+}
+
 func (e *EvalContent) String() string {
 	return fmt.Sprintf("EvalContent{%s}", e.Text)
 }
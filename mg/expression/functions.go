@@ -0,0 +1,210 @@
+package expression
+
+import (
+	"fmt"
+	"go/ast"
+	"go/scanner"
+	"go/token"
+	"html"
+	"math"
+	"net/url"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Function is a value that can be called from an expression, as in `name(arg1, arg2)`.
+type Function func(args []interface{}) (interface{}, error)
+
+// RawString is a string that is written as it is, without being escaped, even where values are escaped
+// automatically (as in HTML files).
+type RawString string
+
+// builtinFunctions are the functions available to every expression.
+var builtinFunctions = map[string]Function{
+	"range":      rangeFunction,
+	"raw":        rawFunction,
+	"escapeHtml": escapeFunction("escapeHtml", EscapeHTML),
+	"escapeAttr": escapeFunction("escapeAttr", EscapeAttr),
+	"escapeJs":   escapeFunction("escapeJs", template.JSEscapeString),
+	"escapeCss":  escapeFunction("escapeCss", EscapeCSS),
+	"urlEncode":  escapeFunction("urlEncode", url.QueryEscape),
+}
+
+// MaxRangeLength is the maximum number of elements the range function can return.
+const MaxRangeLength = 100000
+
+// keywordFunctions maps functions whose names are Go keywords to the name they are parsed as.
+var keywordFunctions = map[token.Token]string{
+	token.RANGE: "range_",
+}
+
+func init() {
+	for tok, name := range keywordFunctions {
+		builtinFunctions[name] = builtinFunctions[tok.String()]
+	}
+}
+
+// renameKeywordFunctions renames calls to functions whose names are Go keywords (e.g. range),
+// as the Go parser would not accept them otherwise.
+func renameKeywordFunctions(expr string) string {
+	var s scanner.Scanner
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(expr))
+	s.Init(file, []byte(expr), nil, 0)
+	var b strings.Builder
+	last := 0
+	for {
+		pos, tok, _ := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if name, ok := keywordFunctions[tok]; ok {
+			offset := file.Offset(pos)
+			b.WriteString(expr[last:offset])
+			b.WriteString(name)
+			last = offset + len(tok.String())
+		}
+	}
+	if last == 0 {
+		return expr
+	}
+	b.WriteString(expr[last:])
+	return b.String()
+}
+
+func resolveCallExpr(expr *ast.CallExpr, ctx Context) (interface{}, error) {
+	ident, ok := expr.Fun.(*ast.Ident)
+	if !ok {
+		return nil, fmt.Errorf("cannot call non-function: %v", expr.Fun)
+	}
+	fun, err := resolveFunction(ident.Name, ctx)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, len(expr.Args))
+	for i, a := range expr.Args {
+		v, err := eval(a, ctx)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return fun(args)
+}
+
+func resolveFunction(name string, ctx Context) (Function, error) {
+	if ctx != nil {
+		if v, ok := ctx.Get(name); ok {
+			if f, ok := v.(Function); ok {
+				return f, nil
+			}
+			return nil, fmt.Errorf("%s is not a function: %v", name, v)
+		}
+	}
+	if f, ok := builtinFunctions[name]; ok {
+		return f, nil
+	}
+	return nil, fmt.Errorf("unknown function: %s", name)
+}
+
+// IsFunction returns whether the given name refers to a function, either a built-in one or one defined
+// in the given context.
+func IsFunction(name string, ctx Context) bool {
+	_, err := resolveFunction(name, ctx)
+	return err == nil
+}
+
+// rangeFunction returns an array with all numbers from a start value to an end value (inclusive),
+// optionally taking a step as a third argument.
+func rangeFunction(args []interface{}) (interface{}, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf("range expects 2 or 3 arguments (start, end [, step]), got %d", len(args))
+	}
+	nums := make([]float64, len(args))
+	for i, a := range args {
+		n, ok := a.(float64)
+		if !ok {
+			return nil, fmt.Errorf("range arguments must be numbers, got %v", a)
+		}
+		nums[i] = n
+	}
+	start, end, step := nums[0], nums[1], 1.0
+	if start > end {
+		step = -1.0
+	}
+	if len(nums) == 3 {
+		step = nums[2]
+		if step == 0 || (step > 0 && start > end) || (step < 0 && start < end) {
+			return nil, fmt.Errorf("range step %v cannot go from %v to %v", step, start, end)
+		}
+	}
+	length := math.Floor(math.Abs((end-start)/step)) + 1
+	if length > MaxRangeLength {
+		return nil, fmt.Errorf("range from %v to %v with step %v has too many elements (maximum is %d)",
+			start, end, step, MaxRangeLength)
+	}
+	result := make([]interface{}, 0, int(length))
+	for n := start; (step > 0 && n <= end) || (step < 0 && n >= end); n += step {
+		result = append(result, n)
+	}
+	return result, nil
+}
+
+// rawFunction marks a string as raw, so that it is not escaped when written.
+func rawFunction(args []interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("raw expects 1 argument, got %d", len(args))
+	}
+	if s, ok := args[0].(string); ok {
+		return RawString(s), nil
+	}
+	return args[0], nil
+}
+
+// escapeFunction returns a function that escapes its single argument with the given escape function.
+//
+// As the result is already escaped, it is a RawString.
+func escapeFunction(name string, escape func(string) string) Function {
+	return func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s expects 1 argument, got %d", name, len(args))
+		}
+		switch v := args[0].(type) {
+		case nil:
+			return RawString(""), nil
+		case string:
+			return RawString(escape(v)), nil
+		case RawString:
+			return RawString(escape(string(v))), nil
+		}
+		return RawString(escape(fmt.Sprint(args[0]))), nil
+	}
+}
+
+// EscapeHTML escapes a string for use as HTML text or within a quoted attribute value.
+func EscapeHTML(s string) string {
+	return html.EscapeString(s)
+}
+
+var attrEscaper = strings.NewReplacer("`", "&#96;", "=", "&#61;", " ", "&#32;", "\t", "&#9;", "\n", "&#10;",
+	"\r", "&#13;", "\f", "&#12;")
+
+// EscapeAttr escapes a string for use as an attribute value, including unquoted attribute values.
+func EscapeAttr(s string) string {
+	return attrEscaper.Replace(html.EscapeString(s))
+}
+
+// EscapeCSS escapes a string for use within a CSS value, escaping all characters which could end the value,
+// such as ';', '}' and '<', with CSS escape sequences.
+func EscapeCSS(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(" #%,.-_", r) {
+			b.WriteRune(r)
+		} else {
+			fmt.Fprintf(&b, "\\%x ", r)
+		}
+	}
+	return b.String()
+}
@@ -22,9 +22,9 @@ func op(x interface{}, y interface{}, no numberOp, so stringOp, to timeOp, oe op
 		}
 	}
 	if so != nil {
-		xs, ok := x.(string)
+		xs, ok := asString(x)
 		if ok {
-			ys, ok := y.(string)
+			ys, ok := asString(y)
 			if ok {
 				return so(xs, ys), nil
 			}
@@ -42,6 +42,33 @@ func op(x interface{}, y interface{}, no numberOp, so stringOp, to timeOp, oe op
 	return oe()
 }
 
+// asString returns the value of a string or of a RawString.
+func asString(v interface{}) (s string, ok bool) {
+	switch str := v.(type) {
+	case string:
+		return str, true
+	case RawString:
+		return string(str), true
+	}
+	return "", false
+}
+
+// rawText returns the value of a RawString, or the HTML-escaped text of any other value.
+func rawText(v interface{}) string {
+	if s, ok := v.(RawString); ok {
+		return string(s)
+	}
+	return EscapeHTML(fmt.Sprintf("%v", v))
+}
+
+// unraw converts a RawString to a string, so that it can be compared with strings.
+func unraw(v interface{}) interface{} {
+	if s, ok := v.(RawString); ok {
+		return string(s)
+	}
+	return v
+}
+
 func bop(x interface{}, y interface{}, bo boolOp, oe opOther) (interface{}, error) {
 	if bo != nil {
 		xf, ok := x.(bool)
@@ -56,6 +83,12 @@ func bop(x interface{}, y interface{}, bo boolOp, oe opOther) (interface{}, erro
 }
 
 func add(x interface{}, y interface{}) (interface{}, error) {
+	_, xRaw := x.(RawString)
+	_, yRaw := y.(RawString)
+	if xRaw || yRaw {
+		// only the raw operands may be written without escaping
+		return RawString(rawText(x) + rawText(y)), nil
+	}
 	return op(x, y, func(x float64, y float64) interface{} {
 		return x + y
 	}, func(x string, y string) interface{} {
@@ -100,11 +133,11 @@ func rem(x interface{}, y interface{}) (interface{}, error) {
 }
 
 func Equal(x interface{}, y interface{}) (interface{}, error) {
-	return reflect.DeepEqual(x, y), nil
+	return reflect.DeepEqual(unraw(x), unraw(y)), nil
 }
 
 func NotEqual(x interface{}, y interface{}) (interface{}, error) {
-	return !reflect.DeepEqual(x, y), nil
+	return !reflect.DeepEqual(unraw(x), unraw(y)), nil
 }
 
 func Less(x interface{}, y interface{}) (interface{}, error) {
@@ -176,7 +209,7 @@ func and(x interface{}, y interface{}) (interface{}, error) {
 }
 
 func or(x interface{}, y interface{}) (interface{}, error) {
-	if x == nil || x == float64(0) || x == false || x == "" {
+	if x == nil || x == float64(0) || x == false || x == "" || x == RawString("") {
 		return y, nil
 	}
 	return x, nil
@@ -81,6 +81,7 @@ func ParseExpr(expr string) (Expression, error) {
 	if strings.HasPrefix(expr, "[") && strings.HasSuffix(expr, "]") {
 		expr = fmt.Sprintf("[]interface{}{%s}", expr[1:len(expr)-1])
 	}
+	expr = renameKeywordFunctions(expr)
 	e, err := parser.ParseExpr(expr)
 	if err != nil {
 		return Expression{}, err
@@ -122,6 +123,8 @@ func eval(e ast.Expr, context Context) (interface{}, error) {
 		return resolveAccessField(ex, context)
 	case *ast.IndexExpr:
 		return resolveIndexExpr(ex, context)
+	case *ast.CallExpr:
+		return resolveCallExpr(ex, context)
 	}
 
 	return nil, fmt.Errorf("Unrecognized expression: %s", e)
@@ -267,7 +270,6 @@ func resolveIndexExpr(expr *ast.IndexExpr, ctx Context) (interface{}, error) {
 			}
 			return nil, err
 		}
-		return nil, fmt.Errorf("unsupported index expression (only date[] and path[] supported): %v", rcv)
 	case *ast.IndexExpr:
 		if i, ok := rcv.X.(*ast.Ident); ok {
 			if i.Name == "date" {
@@ -291,10 +293,38 @@ func resolveIndexExpr(expr *ast.IndexExpr, ctx Context) (interface{}, error) {
 				return nil, errors.New("malformed date expression (should be like date[\"2006-01-02T15:04:00\"][layout])")
 			}
 		}
-		return nil, errors.New("unsupported index expression (only date[][] supported)")
 	}
 
-	return nil, errors.New("malformed index expression (only date[][] supported)")
+	return resolveIndexAccess(expr, ctx)
+}
+
+// resolveIndexAccess evaluates an index expression on a value, e.g. array[0] or object["key"].
+func resolveIndexAccess(expr *ast.IndexExpr, ctx Context) (interface{}, error) {
+	rcv, err := eval(expr.X, ctx)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := eval(expr.Index, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if array, ok := rcv.([]interface{}); ok {
+		if i, ok := idx.(float64); ok {
+			if i < 0 || int(i) >= len(array) {
+				return nil, nil
+			}
+			return array[int(i)], nil
+		}
+		return nil, fmt.Errorf("array index must be a number: %v", idx)
+	}
+	if c, ok := ToContext(rcv, ctx); ok {
+		if key, ok := idx.(string); ok {
+			v, _ := c.Get(key)
+			return v, nil
+		}
+		return nil, fmt.Errorf("property name must be a string: %v", idx)
+	}
+	return nil, fmt.Errorf("cannot index value [%v]: %v", expr.X, rcv)
 }
 
 func parseDate(idx string, format string) (*DateTime, error) {
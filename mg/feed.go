@@ -0,0 +1,305 @@
+package mg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/renatoathaydes/magnanimous/mg/expression"
+)
+
+const (
+	// RSSFeedFileName is the name of the RSS 2.0 feed file written to a feed's directory.
+	RSSFeedFileName = "feed.xml"
+	// AtomFeedFileName is the name of the Atom feed file written to a feed's directory.
+	AtomFeedFileName = "atom.xml"
+	// TitleContextName is the name of the variable holding the title of a file or, globally, of the website.
+	TitleContextName = "title"
+	// DescriptionContextName is the name of the global variable holding the description of the website.
+	DescriptionContextName = "description"
+	// DateContextName is the name of the variable holding the date of a file.
+	DateContextName = "date"
+	// AuthorContextName is the name of the variable holding the author of a file or, globally, of the website.
+	AuthorContextName = "author"
+)
+
+// Feed is a directory whose processed files are published as a RSS and/or Atom feed.
+type Feed struct {
+	// Dir is the directory, relative to the "processed" directory, containing the feed's entries.
+	Dir string
+	// RSS enables writing the RSS 2.0 feed file.
+	RSS bool
+	// Atom enables writing the Atom feed file.
+	Atom bool
+}
+
+// ParseFeed parses a feed specification of the form dir[:rss|:atom].
+//
+// If no format is given, both RSS and Atom feeds are enabled.
+func ParseFeed(spec string) (Feed, error) {
+	dir, format := spec, ""
+	if i := strings.LastIndex(spec, ":"); i >= 0 {
+		dir, format = spec[:i], spec[i+1:]
+	}
+	if strings.TrimSpace(dir) == "" {
+		return Feed{}, fmt.Errorf("feed directory is missing in '%s'", spec)
+	}
+	switch format {
+	case "":
+		return Feed{Dir: dir, RSS: true, Atom: true}, nil
+	case "rss":
+		return Feed{Dir: dir, RSS: true}, nil
+	case "atom":
+		return Feed{Dir: dir, Atom: true}, nil
+	}
+	return Feed{}, fmt.Errorf("unknown feed format '%s' (expected rss or atom)", format)
+}
+
+type feedEntry struct {
+	title   string
+	link    string
+	date    time.Time
+	summary string
+	author  string
+	content string
+}
+
+type rss struct {
+	XMLName       xml.Name  `xml:"rss"`
+	Version       string    `xml:"version,attr"`
+	ContentNS     string    `xml:"xmlns:content,attr"`
+	DCNS          string    `xml:"xmlns:dc,attr"`
+	Title         string    `xml:"channel>title"`
+	Link          string    `xml:"channel>link"`
+	Description   string    `xml:"channel>description"`
+	LastBuildDate string    `xml:"channel>lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"channel>item"`
+}
+
+type rssItem struct {
+	Title   string `xml:"title"`
+	Link    string `xml:"link"`
+	GUID    string `xml:"guid"`
+	PubDate string `xml:"pubDate"`
+	// Author is the email address of the author, as required by RSS 2.0.
+	Author string `xml:"author,omitempty"`
+	// Creator is the name of the author, for authors without an email address.
+	Creator     string `xml:"dc:creator,omitempty"`
+	Description string `xml:"description"`
+	Content     string `xml:"content:encoded"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Links   []atomLink  `xml:"link"`
+	Updated string      `xml:"updated"`
+	Author  *atomAuthor `xml:"author,omitempty"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Link    atomLink    `xml:"link"`
+	Updated string      `xml:"updated"`
+	Author  *atomAuthor `xml:"author,omitempty"`
+	Summary *atomText   `xml:"summary,omitempty"`
+	Content atomText    `xml:"content"`
+}
+
+// writeFeeds writes the RSS and Atom feeds of all configured feed directories.
+//
+// Each published file directly inside a feed directory, except the directory's index file, becomes an entry of
+// the feed, taking its title, date, summary, author and content from its own context. Entries are sorted by date,
+// most recent first. Files without a title use their name, and files without a date use their last updated time.
+func (mag *Magnanimous) writeFeeds(out Output, filesMap WebFilesMap, stack *ContextStack) error {
+	if len(mag.Feeds) == 0 {
+		return nil
+	}
+	siteURL := siteURL(stack, "feeds")
+	files := mag.publishedFiles(filesMap, stack)
+	resolver := mag.newResolver(&filesMap)
+
+	for _, feed := range mag.Feeds {
+		feedDir := filepath.Join(mag.SourcesDir, "processed", feed.Dir)
+		var entries []feedEntry
+		for _, wf := range files {
+			if filepath.Dir(wf.file.Processed.Path) != feedDir || isIndexFile(wf.file.Processed.Path) {
+				continue
+			}
+			entries = append(entries, newFeedEntry(wf, siteURL, stack, &resolver))
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].date.After(entries[j].date)
+		})
+		targetDir := feed.Dir
+		link := siteURL + filepath.ToSlash(filepath.Join("/", feed.Dir)) + "/"
+		if feed.RSS {
+			err := writeXMLFile(out, filepath.Join(targetDir, RSSFeedFileName), newRSS(link, entries, stack))
+			if err != nil {
+				return err
+			}
+		}
+		if feed.Atom {
+			feedURL := link + AtomFeedFileName
+			err := writeXMLFile(out, filepath.Join(targetDir, AtomFeedFileName), newAtomFeed(link, feedURL, entries, stack))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func newFeedEntry(wf webFileWithContext, siteURL string, stack *ContextStack, resolver FileResolver) feedEntry {
+	file := wf.file.Processed
+	// only the file's own variables are used, not variables with the same name in the global context
+	ctx := wf.context.ToStack().Top()
+	entry := feedEntry{
+		title:   globalString(ctx, TitleContextName),
+		link:    siteURL + filepath.ToSlash(fileLink(file)),
+		date:    file.LastUpdated,
+		summary: globalString(ctx, SummaryProperty),
+		author:  globalString(ctx, AuthorContextName),
+		content: globalString(ctx, ContentProperty),
+	}
+	if entry.title == "" {
+		entry.title = wf.file.Name
+	}
+	if v, ok := ctx.Get(DateContextName); ok {
+		if d, ok := v.(*expression.DateTime); ok {
+			if d.Time != nil {
+				entry.date = *d.Time
+			} else if f, err := getInclusionByPath(&pathInclusion{file.GetLocation(), d.Path},
+				resolver, stack, false); err == nil {
+				entry.date = f.Processed.LastUpdated
+			} else {
+				log.Printf("WARNING: (%s) cannot resolve date for feed: %s", file.Path, err)
+			}
+		} else if v != nil {
+			log.Printf("WARNING: ignoring variable %s in file %s as it is not a date: %v",
+				DateContextName, file.Path, v)
+		}
+	}
+	return entry
+}
+
+// isIndexFile returns true if the file at the given path is the index file of its directory (e.g. index.html).
+func isIndexFile(path string) bool {
+	name := filepath.Base(path)
+	return strings.TrimSuffix(name, filepath.Ext(name)) == "index"
+}
+
+func newRSS(link string, entries []feedEntry, stack *ContextStack) rss {
+	feed := rss{
+		Version:     "2.0",
+		ContentNS:   "http://purl.org/rss/1.0/modules/content/",
+		DCNS:        "http://purl.org/dc/elements/1.1/",
+		Title:       globalString(stack, TitleContextName),
+		Link:        link,
+		Description: globalString(stack, DescriptionContextName),
+	}
+	if len(entries) > 0 {
+		feed.LastBuildDate = entries[0].date.Format(time.RFC1123Z)
+	}
+	for _, e := range entries {
+		description := e.summary
+		if description == "" {
+			description = e.content
+		}
+		item := rssItem{
+			Title:       e.title,
+			Link:        e.link,
+			GUID:        e.link,
+			PubDate:     e.date.Format(time.RFC1123Z),
+			Description: description,
+			Content:     e.content,
+		}
+		// RSS requires an email address in the author element, as in "jane@example.org (Jane Doe)"
+		if strings.Contains(e.author, "@") {
+			item.Author = e.author
+		} else {
+			item.Creator = e.author
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	return feed
+}
+
+func newAtomFeed(link, feedURL string, entries []feedEntry, stack *ContextStack) atomFeed {
+	feed := atomFeed{
+		XMLNS: "http://www.w3.org/2005/Atom",
+		Title: globalString(stack, TitleContextName),
+		ID:    link,
+		Links: []atomLink{{Href: link}, {Href: feedURL, Rel: "self"}},
+	}
+	if author := globalString(stack, AuthorContextName); author != "" {
+		feed.Author = &atomAuthor{Name: author}
+	}
+	if len(entries) > 0 {
+		feed.Updated = entries[0].date.UTC().Format(time.RFC3339)
+	} else {
+		feed.Updated = time.Now().UTC().Format(time.RFC3339)
+	}
+	for _, e := range entries {
+		entry := atomEntry{
+			Title:   e.title,
+			ID:      e.link,
+			Link:    atomLink{Href: e.link},
+			Updated: e.date.UTC().Format(time.RFC3339),
+			Content: atomText{Type: "html", Body: e.content},
+		}
+		if e.author != "" {
+			entry.Author = &atomAuthor{Name: e.author}
+		}
+		if e.summary != "" {
+			entry.Summary = &atomText{Type: "html", Body: e.summary}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
+
+// writeXMLFile writes the XML encoding of the given value, with the standard XML header, to the target file
+// of the given output.
+func writeXMLFile(out Output, targetFile string, value interface{}) error {
+	log.Printf("Creating file %s", targetFile)
+	err := writeOutputFile(out, targetFile, func(w io.Writer) error {
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+		encoder := xml.NewEncoder(w)
+		encoder.Indent("", "  ")
+		if err := encoder.Encode(value); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, "\n")
+		return err
+	})
+	if err != nil {
+		return &MagnanimousError{Code: IOError, message: err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package mg
+
+import (
+	"bytes"
+	"html"
+	"log"
+	"math"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/renatoathaydes/magnanimous/mg/expression"
+)
+
+const (
+	// URLProperty is the name of the file context property holding the link to a file,
+	// relative to the root of the website.
+	URLProperty = "url"
+	// NameProperty is the name of the file context property holding the name of a source file.
+	NameProperty = "name"
+	// PathProperty is the name of the file context property holding the path of a source file,
+	// relative to the "processed" directory.
+	PathProperty = "path"
+	// DirProperty is the name of the file context property holding the directory of a source file,
+	// relative to the "processed" directory.
+	DirProperty = "dir"
+	// ExtProperty is the name of the file context property holding the extension of a source file.
+	ExtProperty = "ext"
+	// LastUpdatedProperty is the name of the file context property holding the time a source file was last updated.
+	LastUpdatedProperty = "lastUpdated"
+	// LastUpdatedByProperty is the name of the file context property holding the author of the last change to
+	// a source file, which is only available when the git history is used.
+	LastUpdatedByProperty = "lastUpdatedBy"
+	// SizeProperty is the name of the file context property holding the size of a source file, in bytes.
+	SizeProperty = "size"
+	// ContentProperty is the name of the file context property holding the rendered contents of a file.
+	ContentProperty = "content"
+	// SummaryProperty is the name of the file context property holding the summary of a file.
+	//
+	// The summary is the rendered content before the SummaryMarker or, if there's no marker,
+	// the first paragraph of the file.
+	SummaryProperty = "summary"
+	// WordCountProperty is the name of the file context property holding the number of words in a file.
+	WordCountProperty = "wordCount"
+	// ReadingTimeProperty is the name of the file context property holding the estimated time,
+	// in minutes, to read a file.
+	ReadingTimeProperty = "readingTime"
+	// SummaryMarker marks the end of the summary of a file.
+	SummaryMarker = "<!--more-->"
+	// WordsPerMinute is the reading speed used to compute the reading time of a file.
+	WordsPerMinute = 200
+)
+
+var (
+	htmlTagRegex   = regexp.MustCompile(`<[^>]*>`)
+	paragraphRegex = regexp.MustCompile(`(?s)<p[\s>].*?</p>`)
+)
+
+// fileContext is the Context of a file, as seen by other files (e.g. when iterating over files in a for loop).
+//
+// Besides the values defined by the file itself, it provides the file's metadata (e.g. url and lastUpdated) and
+// properties computed from the file's rendered contents. Properties are only computed when first requested,
+// and values defined by the file with the same name take precedence.
+//
+// While the file's own definitions are resolved, properties are hidden, so that variables with the same names
+// (e.g. defined in the global context) are found instead.
+type fileContext struct {
+	mapContext
+	file *ProcessedFile
+	// base is the Context in which the file is rendered
+	base     Context
+	rendered *string
+	// resolving is true while the file's definitions are resolved
+	resolving bool
+}
+
+var _ Context = (*fileContext)(nil)
+
+func (f *fileContext) Get(name string) (interface{}, bool) {
+	if v, ok := f.mapContext.Get(name); ok {
+		return v, true
+	}
+	if f.file == nil || f.resolving {
+		return nil, false
+	}
+	switch name {
+	case URLProperty:
+		return filepath.ToSlash(fileLink(f.file)), true
+	case NameProperty:
+		return filepath.Base(f.file.Path), true
+	case PathProperty:
+		return f.sourcePath(), true
+	case DirProperty:
+		return path.Dir(f.sourcePath()), true
+	case ExtProperty:
+		return filepath.Ext(f.file.Path), true
+	case LastUpdatedProperty:
+		lastUpdated := f.file.LastUpdated
+		return &expression.DateTime{Time: &lastUpdated, Format: expression.DefaultDateTimeFormat}, true
+	case LastUpdatedByProperty:
+		if f.file.LastUpdatedBy == "" {
+			return nil, false
+		}
+		return f.file.LastUpdatedBy, true
+	case SizeProperty:
+		return float64(f.file.Size), true
+	case ContentProperty:
+		// the content is already rendered, so it must not be escaped again
+		return expression.RawString(f.content()), true
+	case SummaryProperty:
+		return expression.RawString(summary(f.content())), true
+	case WordCountProperty:
+		return float64(wordCount(f.content())), true
+	case ReadingTimeProperty:
+		return math.Ceil(float64(wordCount(f.content())) / WordsPerMinute), true
+	}
+	return nil, false
+}
+
+// sourcePath returns the path of the source file relative to its base path, e.g. /posts/my-post.md.
+func (f *fileContext) sourcePath() string {
+	p, err := filepath.Rel(f.file.BasePath, f.file.Path)
+	if err != nil {
+		p = f.file.Path
+	}
+	return path.Join("/", filepath.ToSlash(p))
+}
+
+// content renders the file, or returns the empty String if that fails, e.g. because
+// the file is currently being rendered (which would cause an infinite cycle).
+func (f *fileContext) content() string {
+	if f.rendered != nil {
+		return *f.rendered
+	}
+	var result string
+	stack := f.base.ToStack()
+	err := detectCycle(stack, f.file.Path, f.file.Path, f.file.GetLocation())
+	if err == nil {
+		var b bytes.Buffer
+		wf := WebFile{BasePath: filepath.Dir(f.file.Path), Name: filepath.Base(f.file.Path), Processed: f.file}
+		err = wf.Write(&b, stack, true, false)
+		result = b.String()
+	}
+	if err != nil {
+		log.Printf("WARNING: (%s) unable to render file contents: %s", f.file.Path, err)
+		result = ""
+	}
+	f.rendered = &result
+	return result
+}
+
+func summary(content string) string {
+	if i := strings.Index(content, SummaryMarker); i >= 0 {
+		s := strings.TrimSpace(content[:i])
+		// in markdown, the marker may end up within a paragraph
+		if strings.LastIndex(s, "<p>") > strings.LastIndex(s, "</p>") {
+			s += "</p>"
+		}
+		return s
+	}
+	if p := paragraphRegex.FindString(content); p != "" {
+		return p
+	}
+	// not HTML, use the first block of text
+	content = strings.TrimSpace(content)
+	if i := strings.Index(content, "\n\n"); i >= 0 {
+		return content[:i]
+	}
+	return content
+}
+
+func wordCount(content string) int {
+	text := html.UnescapeString(htmlTagRegex.ReplaceAllString(content, " "))
+	return len(strings.Fields(text))
+}
@@ -1,7 +1,8 @@
 package mg
 
 import (
-	"os"
+	"errors"
+	"io/fs"
 	"path/filepath"
 	"strings"
 )
@@ -12,16 +13,23 @@ import (
 //
 // It can also resolve up-paths, i.e. paths starting with '.../', which resolve to a existing file in
 // the current directory or a parent directory, up until the file is found, or the BasePath is reached.
+//
+// If Sandboxed is true, paths going up from the BasePath are not limited to it, but rejected, as are paths to
+// symbolic links whose targets are outside the BasePath.
 type DefaultFileResolver struct {
-	BasePath string
-	Files    *WebFilesMap
+	BasePath  string
+	Files     *WebFilesMap
+	Sandboxed bool
+	// source is the file system files are read from (see [Magnanimous.Source]).
+	source fs.FS
 }
 
 var _ FileResolver = (*DefaultFileResolver)(nil)
 
 type filesCollector func() ([]string, error)
 
-func collectFiles(sourcesDir, processedDir, staticDir string) (procFiles, staticFiles, otherFiles []string) {
+func collectFiles(fsys fs.FS, sourcesDir, processedDir,
+	staticDir string) (procFiles, staticFiles, otherFiles []string) {
 	async := func(fc filesCollector, c chan []string) {
 		s, err := fc()
 		if err != nil {
@@ -31,17 +39,17 @@ func collectFiles(sourcesDir, processedDir, staticDir string) (procFiles, static
 	}
 
 	procC, statC, othersC := make(chan []string), make(chan []string), make(chan []string)
-	go async(func() ([]string, error) { return getFilesAt(processedDir) }, procC)
-	go async(func() ([]string, error) { return getFilesAt(staticDir) }, statC)
+	go async(func() ([]string, error) { return getFilesAt(fsys, processedDir) }, procC)
+	go async(func() ([]string, error) { return getFilesAt(fsys, staticDir) }, statC)
 	go async(func() ([]string, error) {
-		return getFilesAt(sourcesDir, processedDir, staticDir)
+		return getFilesAt(fsys, sourcesDir, processedDir, staticDir)
 	}, othersC)
 
 	procFiles, staticFiles, otherFiles = <-procC, <-statC, <-othersC
 	return
 }
 
-func getFilesAt(root string, exclusions ...string) ([]string, error) {
+func getFilesAt(fsys fs.FS, root string, exclusions ...string) ([]string, error) {
 	var files []string
 	notExcluded := func(path string) bool {
 		for _, e := range exclusions {
@@ -51,13 +59,18 @@ func getFilesAt(root string, exclusions ...string) ([]string, error) {
 		}
 		return true
 	}
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err == nil && !info.IsDir() && notExcluded(path) {
+	name := filepath.ToSlash(root)
+	if name == "" {
+		name = "."
+	}
+	err := fs.WalkDir(fsys, name, func(path string, entry fs.DirEntry, err error) error {
+		path = filepath.FromSlash(path)
+		if err == nil && !entry.IsDir() && notExcluded(path) {
 			files = append(files, path)
 		}
 		return err
 	})
-	if os.IsNotExist(err) {
+	if errors.Is(err, fs.ErrNotExist) {
 		return []string{}, nil
 	}
 	return files, err
@@ -65,6 +78,9 @@ func getFilesAt(root string, exclusions ...string) ([]string, error) {
 
 func (r *DefaultFileResolver) FilesIn(dir string, from *Location) (dirPath string, webFiles []WebFile, e error) {
 	dirPath = r.Resolve(dir, from, nil)
+	if e = r.checkPath(dirPath); e != nil {
+		return
+	}
 	for path, wf := range r.Files.WebFiles {
 		if !wf.NonWritable && filepath.Dir(path) == dirPath {
 			webFiles = append(webFiles, wf)
@@ -89,6 +105,10 @@ func (r *DefaultFileResolver) Resolve(path string, from, at *Location) string {
 
 	// relative path
 	p := filepath.Join(filepath.Dir(from.Origin), path)
+	if r.Sandboxed {
+		// paths going outside the base path are rejected by checkPath
+		return p
+	}
 
 	// must not go up higher than basePath
 	for strings.HasPrefix(p, "../") {
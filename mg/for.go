@@ -5,6 +5,8 @@ import (
 	"io"
 	"log"
 	"strings"
+
+	"github.com/renatoathaydes/magnanimous/mg/expression"
 )
 
 type ForLoop struct {
@@ -87,7 +89,17 @@ func (f *ForLoop) Write(writer io.Writer, context Context) ([]Content, error) {
 
 func (f *ForLoop) resolveIterable(context Context) (iterable iterable, ok bool) {
 	gIter := f.iter
-	arg := pathOrEval(gIter.arg, context)
+	var arg interface{}
+	if call := functionCallRegex.FindStringSubmatch(gIter.arg); call != nil && expression.IsFunction(call[1], context) {
+		var err error
+		arg, err = expression.Eval(gIter.arg, context)
+		if err != nil {
+			log.Printf("WARNING: (%s) for-loop expression error: %s", f.Location.String(), err.Error())
+			return iterable, false
+		}
+	} else {
+		arg = pathOrEval(gIter.arg, context)
+	}
 	switch a := arg.(type) {
 	case string:
 		dirIter := directoryIterable{path: a, location: gIter.location, resolver: gIter.resolver,
@@ -105,12 +117,17 @@ func (f *ForLoop) resolveIterable(context Context) (iterable iterable, ok bool)
 	case []interface{}:
 		itemsIter := arrayIterable{array: a, location: gIter.location, subInstructions: gIter.subInstructions}
 		iterable.items = itemsIter.getItems(context)
+	case map[string]interface{}:
+		mapIter := mapIterable{entries: a, location: gIter.location, subInstructions: gIter.subInstructions}
+		iterable.items = mapIter.getItems(context)
 	case []webFileWithContext:
-		array := make([]interface{}, len(a))
-		for i, item := range a {
-			array[i] = item.context
+		filesIter := filesIterable{files: a, subInstructions: gIter.subInstructions}
+		files, groupedBy := filesIter.getItems(context)
+		if groupedBy != nil {
+			iterable.groups = groupedBy
+		} else {
+			iterable.files = files
 		}
-		iterable.items = array
 	default:
 		log.Printf("WARNING: (%s) invalid for-loop expression, cannot iterate over: %v", f.Location.String(), arg)
 		return iterable, false
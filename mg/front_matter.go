@@ -0,0 +1,167 @@
+package mg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/renatoathaydes/magnanimous/mg/expression"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	yamlFrontMatterDelimiter = "---"
+	tomlFrontMatterDelimiter = "+++"
+)
+
+// FrontMatter is a Content holding the variables declared in a YAML or TOML block at the top of a file.
+//
+// Like DefineContent, it does not write anything, it only sets its variables into its context.
+type FrontMatter struct {
+	UnscopedContent
+	Values   map[string]interface{}
+	Location *Location
+}
+
+var _ Content = (*FrontMatter)(nil)
+var _ Definition = (*FrontMatter)(nil)
+
+func (f *FrontMatter) GetLocation() *Location {
+	return f.Location
+}
+
+// GetName returns the name used to refer to the front matter in log messages.
+func (f *FrontMatter) GetName() string {
+	return "front-matter"
+}
+
+func (f *FrontMatter) Write(writer io.Writer, context Context) ([]Content, error) {
+	// set the values in a stable order so that behaviour is always the same
+	keys := make([]string, 0, len(f.Values))
+	for k := range f.Values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		context.Set(k, f.Values[k])
+	}
+	return nil, nil
+}
+
+func (f *FrontMatter) Eval(context Context) (interface{}, bool) {
+	return f.Values, true
+}
+
+func (f *FrontMatter) String() string {
+	return fmt.Sprintf("FrontMatter{%v}", f.Values)
+}
+
+// parseFrontMatter parses the front matter block at the start of the reader, if any.
+//
+// If the reader does not start with a front matter delimiter, nothing is consumed and nil is returned.
+// Otherwise, the whole block is consumed and the parser state's row is updated accordingly.
+func parseFrontMatter(state *parserState) (*FrontMatter, error) {
+	delimiter := frontMatterDelimiter(state.reader)
+	if delimiter == "" {
+		return nil, nil
+	}
+	location := Location{Origin: state.file, Row: state.row, Col: state.col}
+
+	// consume the opening delimiter line
+	if _, err := state.reader.ReadString('\n'); err != nil {
+		return nil, &MagnanimousError{Code: IOError, message: err.Error()}
+	}
+	state.row++
+
+	var block strings.Builder
+	for {
+		line, err := state.reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, &MagnanimousError{Code: IOError, message: err.Error()}
+		}
+		if strings.TrimRight(line, "\r\n") == delimiter {
+			state.row++
+			break
+		}
+		if err == io.EOF {
+			return nil, NewError(location, ParseError,
+				fmt.Sprintf("front matter was not closed with '%s'", delimiter))
+		}
+		block.WriteString(line)
+		state.row++
+	}
+
+	values := make(map[string]interface{})
+	var err error
+	if delimiter == yamlFrontMatterDelimiter {
+		err = yaml.Unmarshal([]byte(block.String()), &values)
+	} else {
+		_, err = toml.Decode(block.String(), &values)
+	}
+	if err != nil {
+		return nil, NewError(location, ParseError, fmt.Sprintf("invalid front matter: %s", err.Error()))
+	}
+	for k, v := range values {
+		values[k] = normalizeValue(v)
+	}
+	return &FrontMatter{Values: values, Location: &location}, nil
+}
+
+func frontMatterDelimiter(reader *bufio.Reader) string {
+	for _, delimiter := range []string{yamlFrontMatterDelimiter, tomlFrontMatterDelimiter} {
+		for _, newLine := range []string{"\n", "\r\n"} {
+			start, err := reader.Peek(len(delimiter) + len(newLine))
+			if err == nil && string(start) == delimiter+newLine {
+				return delimiter
+			}
+		}
+	}
+	return ""
+}
+
+// normalizeValue converts values parsed from structured data (e.g. YAML, TOML) to the types used by
+// Magnanimous expressions: numbers become float64, dates become *expression.DateTime, and
+// nested lists and maps are normalized recursively.
+func normalizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case time.Time:
+		return &expression.DateTime{Time: &v, Format: expression.DefaultDateTimeFormat}
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = normalizeValue(item)
+		}
+		return result
+	case []map[string]interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = normalizeValue(item)
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			result[k] = normalizeValue(item)
+		}
+		return result
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			result[fmt.Sprint(k)] = normalizeValue(item)
+		}
+		return result
+	}
+	return value
+}
@@ -0,0 +1,142 @@
+package mg
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Output is where the files of a website are written to (see [Magnanimous.WriteOutput]).
+//
+// If an Output also has a method Stat(name string) (fs.FileInfo, error), as [DirOutput] does, static files are
+// only written to it if they were modified after they were last written.
+//
+// Links can only be checked in an Output that also has a method ReadFile(name string) ([]byte, error),
+// as [DirOutput] and [MemoryOutput] do.
+type Output interface {
+	// Create creates, or truncates, the file with the given slash-separated path, relative to the root of the
+	// output, creating its parent directories if necessary.
+	//
+	// The contents of the file are complete only once the returned writer is closed.
+	Create(name string) (io.WriteCloser, error)
+}
+
+// outputStater is implemented by outputs that can tell when a file was last written.
+type outputStater interface {
+	Stat(name string) (fs.FileInfo, error)
+}
+
+// outputReader is implemented by outputs that can read back the files written to them, as required to check
+// links (see [Magnanimous.FindBrokenLinks]).
+type outputReader interface {
+	ReadFile(name string) ([]byte, error)
+}
+
+// DirOutput is an [Output] writing files to a directory of the operating system's file system.
+type DirOutput string
+
+var _ Output = DirOutput("")
+var _ outputStater = DirOutput("")
+var _ outputReader = DirOutput("")
+
+func (d DirOutput) Create(name string) (io.WriteCloser, error) {
+	file := d.path(name)
+	if err := os.MkdirAll(filepath.Dir(file), 0770); err != nil {
+		return nil, err
+	}
+	return os.Create(file)
+}
+
+func (d DirOutput) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(d.path(name))
+}
+
+func (d DirOutput) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(d.path(name))
+}
+
+func (d DirOutput) path(name string) string {
+	return filepath.Join(string(d), filepath.FromSlash(name))
+}
+
+// MemoryOutput is an [Output] keeping the contents of the files written to it in memory, by their paths.
+type MemoryOutput map[string][]byte
+
+var _ Output = MemoryOutput(nil)
+var _ outputReader = MemoryOutput(nil)
+
+func (m MemoryOutput) Create(name string) (io.WriteCloser, error) {
+	return &memoryFile{name: name, output: m}, nil
+}
+
+func (m MemoryOutput) ReadFile(name string) ([]byte, error) {
+	contents, ok := m[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return contents, nil
+}
+
+type memoryFile struct {
+	bytes.Buffer
+	name   string
+	output MemoryOutput
+}
+
+func (f *memoryFile) Close() error {
+	f.output[f.name] = f.Bytes()
+	return nil
+}
+
+// writeOutputFile creates a file in the given output, then writes its contents with the given function.
+func writeOutputFile(out Output, name string, write func(writer io.Writer) error) error {
+	f, err := out.Create(filepath.ToSlash(name))
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	err = write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// osFS is the operating system's file system, where names are file paths (including absolute paths and
+// paths going up from the current directory, unlike in [os.DirFS]).
+type osFS struct{}
+
+var _ fs.StatFS = osFS{}
+var _ fs.ReadFileFS = osFS{}
+
+func (osFS) Open(name string) (fs.File, error) {
+	return os.Open(filepath.FromSlash(name))
+}
+
+func (osFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(filepath.FromSlash(name))
+}
+
+func (osFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(filepath.FromSlash(name))
+}
+
+// sourceFS returns the file system source files are read from, given the value of [Magnanimous.Source].
+func sourceFS(fsys fs.FS) fs.FS {
+	if fsys == nil {
+		return osFS{}
+	}
+	return fsys
+}
+
+// isOSFS returns whether a file system, given as the value of [Magnanimous.Source], is the operating system's.
+func isOSFS(fsys fs.FS) bool {
+	_, ok := sourceFS(fsys).(osFS)
+	return ok
+}
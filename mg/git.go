@@ -0,0 +1,121 @@
+package mg
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gitCommitPrefix marks the lines of git log's output that describe a commit, rather than a file.
+const gitCommitPrefix = "\x1e"
+
+type gitFileInfo struct {
+	lastUpdated time.Time
+	author      string
+}
+
+// applyGitDates sets the LastUpdated time and LastUpdatedBy author of all files from the git history of the
+// sources directory, using the local git command.
+//
+// Files that are not committed, or that have changes that are not committed, keep their modification times.
+// If the git history cannot be read (e.g. git is not installed), a warning is logged and all files keep their
+// modification times.
+func (mag *Magnanimous) applyGitDates(filesMap WebFilesMap) {
+	infos, err := gitLog(mag.SourcesDir)
+	if err == nil {
+		var changed map[string]bool
+		changed, err = gitChangedFiles(mag.SourcesDir)
+		for file := range changed {
+			delete(infos, file)
+		}
+	}
+	if err != nil {
+		log.Printf("WARNING: unable to read git history, using file modification times instead: %s", err)
+		return
+	}
+	for file, wf := range filesMap.WebFiles {
+		rel, err := filepath.Rel(mag.SourcesDir, file)
+		if err != nil {
+			continue
+		}
+		if info, ok := infos[filepath.ToSlash(rel)]; ok {
+			wf.Processed.LastUpdated = info.lastUpdated
+			wf.Processed.LastUpdatedBy = info.author
+		}
+	}
+}
+
+// gitLog returns the last commit of each file in the given directory, by path relative to the directory.
+//
+// As shallow clones (e.g. made by CI systems) lack older commits, a warning is logged for them.
+func gitLog(dir string) (map[string]gitFileInfo, error) {
+	if out, err := runGit(dir, "rev-parse", "--is-shallow-repository"); err == nil &&
+		strings.TrimSpace(string(out)) == "true" {
+		log.Printf("WARNING: the git repository at %s is a shallow clone, so files last changed in commits "+
+			"missing from it get wrong dates. Fetch the full history (e.g. git fetch --unshallow) to fix that.", dir)
+	}
+	out, err := runGit(dir, "log", "--relative", "--name-only", "--no-renames",
+		"--format="+gitCommitPrefix+"%at %an", "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	infos := make(map[string]gitFileInfo)
+	var current gitFileInfo
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, gitCommitPrefix) {
+			parts := strings.SplitN(line[len(gitCommitPrefix):], " ", 2)
+			seconds, err := strconv.ParseInt(parts[0], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected git log output: %s", line)
+			}
+			current = gitFileInfo{lastUpdated: time.Unix(seconds, 0)}
+			if len(parts) == 2 {
+				current.author = parts[1]
+			}
+		} else if line != "" {
+			// commits are listed from the most recent, so only the first time a file appears matters
+			if _, ok := infos[line]; !ok {
+				infos[line] = current
+			}
+		}
+	}
+	return infos, scanner.Err()
+}
+
+// gitChangedFiles returns the files in the given directory which differ from the last commit.
+func gitChangedFiles(dir string) (map[string]bool, error) {
+	out, err := runGit(dir, "diff", "HEAD", "--relative", "--name-only", "--no-renames", "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	changed := make(map[string]bool)
+	for _, line := range strings.Split(string(out), "\n") {
+		if line != "" {
+			changed[line] = true
+		}
+	}
+	return changed, nil
+}
+
+func runGit(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-c", "core.quotepath=off"}, args...)...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", err, msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
@@ -17,6 +17,9 @@ func getInclusionByPath(inc Inclusion, resolver FileResolver, context Context, c
 		return nil, fmt.Errorf("path expression evaluated to non-string value: %v", maybePath)
 	}
 	f := resolver.Resolve(actualPath, inc.GetLocation(), context.ToStack().NearestLocation())
+	if err := checkResolvedPath(resolver, f); err != nil {
+		return nil, NewError(*inc.GetLocation(), IOError, fmt.Sprintf("cannot use path %s: %s", actualPath, err))
+	}
 	webFile, ok := resolver.Get(f)
 	if !ok {
 		return nil, fmt.Errorf("path expression refers non-existent resource: %s", actualPath)
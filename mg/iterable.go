@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -38,6 +39,23 @@ func (g GroupByItem) Get(name string) (interface{}, bool) {
 	return nil, false
 }
 
+// MapEntry is a key/value pair of a map being iterated over.
+type MapEntry struct {
+	key   string
+	value interface{}
+}
+
+// Get implements mg.expression.Context.
+func (e MapEntry) Get(name string) (interface{}, bool) {
+	if name == "key" {
+		return e.key, true
+	}
+	if name == "value" {
+		return e.value, true
+	}
+	return nil, false
+}
+
 type iterationContent struct {
 	UnscopedContent
 	variable string
@@ -48,6 +66,14 @@ type iterationContent struct {
 
 var _ Content = (*iterationContent)(nil)
 var _ expression.Context = (*GroupByItem)(nil)
+var _ expression.Context = (*MapEntry)(nil)
+
+// functionCallRegex matches iterables that look like a function call, e.g. range(1, 10), capturing the
+// function name.
+//
+// Such iterables are only evaluated as function calls if the name refers to a function, as they may also
+// be paths, e.g. posts(2020).
+var functionCallRegex = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*)\s*\(.*\)$`)
 
 type forLoopSubInstruction struct {
 	sortBy  *sortBySubInstruction
@@ -84,6 +110,18 @@ type arrayIterable struct {
 	subInstructions []forLoopSubInstruction
 }
 
+type mapIterable struct {
+	entries         map[string]interface{}
+	location        *Location
+	subInstructions []forLoopSubInstruction
+}
+
+// filesIterable is an iterable over files that have already been resolved (e.g. the values of a group).
+type filesIterable struct {
+	files           []webFileWithContext
+	subInstructions []forLoopSubInstruction
+}
+
 type directoryIterable struct {
 	path            string
 	resolver        FileResolver
@@ -136,6 +174,39 @@ func (e *arrayIterable) getItems(_ Context) []interface{} {
 	return array
 }
 
+func (e *mapIterable) getItems(_ Context) []interface{} {
+	keys := make([]string, 0, len(e.entries))
+	for k := range e.entries {
+		keys = append(keys, k)
+	}
+	// map iteration order is random, so always start by sorting by key
+	sort.Strings(keys)
+	items := make([]interface{}, len(keys))
+	for i, k := range keys {
+		items[i] = MapEntry{key: k, value: e.entries[k]}
+	}
+	for _, subInstruction := range e.subInstructions {
+		if sortBy := subInstruction.sortBy; sortBy != nil {
+			sortMapEntries(items, sortBy)
+		}
+		if subInstruction.reverse != nil {
+			reverseArray(items)
+		}
+		if subInstruction.limit != nil {
+			limit := len(items)
+			if subInstruction.limit.max < limit {
+				limit = subInstruction.limit.max
+			}
+			items = items[0:limit]
+		}
+		if subInstruction.groupBy != nil {
+			log.Printf("WARN: (%s) 'groupBy' is not supported when iterating over a map, will ignore it",
+				e.location.String())
+		}
+	}
+	return items
+}
+
 func (e *directoryIterable) getItems(context Context) ([]webFileWithContext, []GroupByItem, error) {
 	webFilesCtx, err := e.filesWithContext(context)
 	if err != nil {
@@ -151,8 +222,21 @@ func (e *directoryIterable) getItems(context Context) ([]webFileWithContext, []G
 		})
 	}
 
+	files, groups := applyFileSubInstructions(webFilesCtx, e.subInstructions)
+	return files, groups, nil
+}
+
+func (e *filesIterable) getItems(_ Context) ([]webFileWithContext, []GroupByItem) {
+	// copy the files so the original order is not affected by the sub-instructions
+	webFilesCtx := make([]webFileWithContext, len(e.files))
+	copy(webFilesCtx, e.files)
+	return applyFileSubInstructions(webFilesCtx, e.subInstructions)
+}
+
+func applyFileSubInstructions(webFilesCtx []webFileWithContext,
+	subInstructions []forLoopSubInstruction) ([]webFileWithContext, []GroupByItem) {
 	var groupBy *groupBySubInstruction
-	for _, subInstruction := range e.subInstructions {
+	for _, subInstruction := range subInstructions {
 		if subInstruction.groupBy != nil {
 			groupBy = subInstruction.groupBy
 			break
@@ -160,15 +244,15 @@ func (e *directoryIterable) getItems(context Context) ([]webFileWithContext, []G
 	}
 
 	if groupBy != nil {
-		if len(e.subInstructions) > 1 {
+		if len(subInstructions) > 1 {
 			log.Printf("WARN: using 'groupBy' in for-loop with other sub-instructions is not supported, " +
 				"will ignore everything else")
 		}
 		groupedItems := groupByArray(webFilesCtx, groupBy.field)
-		return nil, groupedItems, nil
+		return nil, groupedItems
 	}
 
-	for _, subInstruction := range e.subInstructions {
+	for _, subInstruction := range subInstructions {
 		if subInstruction.sortBy != nil {
 			sortField := subInstruction.sortBy.field
 			sortFiles(webFilesCtx, sortField)
@@ -187,7 +271,7 @@ func (e *directoryIterable) getItems(context Context) ([]webFileWithContext, []G
 		}
 	}
 
-	return webFilesCtx, nil, nil
+	return webFilesCtx, nil
 }
 
 func (e *directoryIterable) filesWithContext(context Context) ([]webFileWithContext, error) {
@@ -261,12 +345,48 @@ TopLevelForLoop:
 }
 
 func sortArray(array []interface{}, instruction *sortBySubInstruction) {
-	if instruction.field != "_" {
-		log.Printf("WARN: It is not possible to sort simple array by field (use '_' instead): %s",
+	field := instruction.field
+	if field != "_" && len(array) > 0 {
+		if _, ok := expression.ToContext(array[0], nil); !ok {
+			log.Printf("WARN: It is not possible to sort simple array by field (use '_' instead): %s", field)
+			field = "_"
+		}
+	}
+	value := func(item interface{}) interface{} {
+		if field == "_" {
+			return item
+		}
+		// arrays of objects may be sorted by field
+		if ctx, ok := expression.ToContext(item, nil); ok {
+			v, _ := ctx.Get(field)
+			return v
+		}
+		return item
+	}
+	sort.SliceStable(array, func(i, j int) bool {
+		res, err := expression.Less(value(array[i]), value(array[j]))
+		if err != nil {
+			log.Printf("WARN: %s", err.Error())
+			return false
+		}
+		return res.(bool)
+	})
+}
+
+func sortMapEntries(entries []interface{}, instruction *sortBySubInstruction) {
+	field := instruction.field
+	if field == "_" {
+		field = "key"
+	}
+	if field != "key" && field != "value" {
+		log.Printf("WARN: It is not possible to sort map entries by field (use 'key' or 'value' instead): %s",
 			instruction.field)
+		return
 	}
-	sort.Slice(array, func(i, j int) bool {
-		res, err := expression.Less(array[i], array[j])
+	sort.SliceStable(entries, func(i, j int) bool {
+		iv, _ := entries[i].(MapEntry).Get(field)
+		jv, _ := entries[j].(MapEntry).Get(field)
+		res, err := expression.Less(iv, jv)
 		if err != nil {
 			log.Printf("WARN: %s", err.Error())
 			return false
@@ -286,12 +406,19 @@ func groupByArray(webFiles []webFileWithContext, groupField string) (result []Gr
 		wf := webFiles[i]
 		fv, ok := wf.context.Get(groupField)
 		if ok {
-			key := fmt.Sprint(fv)
-			_, ok := groups[key]
-			if !ok {
-				groupsInOrder = append(groupsInOrder, key)
+			// list-valued fields (e.g. tags) put the file into one group per item
+			values, isList := fv.([]interface{})
+			if !isList {
+				values = []interface{}{fv}
+			}
+			for _, v := range values {
+				key := groupKey(v)
+				_, ok := groups[key]
+				if !ok {
+					groupsInOrder = append(groupsInOrder, key)
+				}
+				groups[key] = append(groups[key], wf)
 			}
-			groups[key] = append(groups[key], wf)
 		} else {
 			log.Printf("WARN: ignoring file in groupBy %s - file %s does not define such property",
 				groupField, webFiles[i].file.Name)
@@ -305,6 +432,14 @@ func groupByArray(webFiles []webFileWithContext, groupField string) (result []Gr
 	return
 }
 
+// groupKey returns the name of the group of a value, formatting dates (e.g. lastUpdated) with their format.
+func groupKey(v interface{}) string {
+	if d, ok := v.(*expression.DateTime); ok && d.Time != nil {
+		return d.Time.Format(d.Format)
+	}
+	return fmt.Sprint(v)
+}
+
 func sortFiles(webFiles []webFileWithContext, sortField string) {
 	sort.Slice(webFiles, func(i, j int) bool {
 		iv, ok := webFiles[i].context.Get(sortField)
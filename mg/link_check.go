@@ -0,0 +1,267 @@
+package mg
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"log"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	linkAttributeRegex   = regexp.MustCompile(`(?i)\s(?:href|src)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	anchorAttributeRegex = regexp.MustCompile(`(?i)\s(?:id|name)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	// rssLinkRegex matches the link elements of RSS feeds, the second group is always empty so that matches are
+	// handled in the same way as matches of linkAttributeRegex.
+	rssLinkRegex = regexp.MustCompile(`<link>([^<]*)</link>()`)
+)
+
+// BrokenLink is a link, found in a generated HTML file or feed, to a target that does not exist.
+type BrokenLink struct {
+	// Location is where the link originates from in the source files.
+	Location Location
+	// Link is the href or src attribute value of the link.
+	Link string
+	// Reason explains why the link is broken.
+	Reason string
+}
+
+func (b BrokenLink) String() string {
+	return fmt.Sprintf("(%s) broken link %s: %s", b.Location.String(), b.Link, b.Reason)
+}
+
+// linkedPage is a file written to the output whose links are checked.
+type linkedPage struct {
+	// targetFile is the slash-separated path of the file within the output.
+	targetFile string
+	// source is the file the page is written from, if any.
+	source *ProcessedFile
+	// origin is the location of the page in the sources, used if it has no source file.
+	origin string
+	links  *regexp.Regexp
+}
+
+// linkChecker checks internal links of files written to an output.
+type linkChecker struct {
+	out outputReader
+	// site is the scheme and host of the siteURL (or baseURL), if it is a full URL.
+	site string
+	// basePath is the path prefix of the website, given by the baseURL variable, always ending with "/".
+	basePath string
+	anchors  map[string]map[string]bool
+}
+
+// CheckLinks checks the links in the files written by [Magnanimous.WriteOutput] to the given output,
+// logging each broken link (see [Magnanimous.FindBrokenLinks]) and returning an error if any is found.
+func (mag *Magnanimous) CheckLinks(out Output, filesMap WebFilesMap) error {
+	broken, err := mag.FindBrokenLinks(out, filesMap)
+	if err != nil {
+		return err
+	}
+	if len(broken) == 0 {
+		log.Println("No broken links found.")
+		return nil
+	}
+	for _, b := range broken {
+		log.Printf("ERROR: %s", b.String())
+	}
+	return &MagnanimousError{Code: ParseError, message: fmt.Sprintf("found %d broken link(s)", len(broken))}
+}
+
+// FindBrokenLinks finds the internal links (href and src attributes) in the HTML files, including taxonomy
+// pages, and in the feeds written by [Magnanimous.WriteOutput] to the given output that refer to a file or
+// #fragment anchor that does not exist in the output.
+//
+// Absolute links must start with the baseURL variable, if defined. External links are not checked.
+//
+// The output must be able to read back the files written to it, as [DirOutput] and [MemoryOutput] can.
+func (mag *Magnanimous) FindBrokenLinks(out Output, filesMap WebFilesMap) ([]BrokenLink, error) {
+	reader, ok := out.(outputReader)
+	if !ok {
+		return nil, &MagnanimousError{Code: IOError,
+			message: fmt.Sprintf("cannot check links as files cannot be read from the output %T", out)}
+	}
+	stack, err := mag.newContextStack(filesMap)
+	if err != nil {
+		return nil, err
+	}
+	site := globalString(&stack, SiteURLContextName)
+	if site == "" {
+		site = globalString(&stack, BaseURLContextName)
+	}
+	c := newLinkChecker(reader, site, globalString(&stack, BaseURLContextName))
+	broken, err := c.check(mag.linkedPages(filesMap, &stack))
+	if err != nil {
+		return nil, &MagnanimousError{Code: IOError, message: err.Error()}
+	}
+	return broken, nil
+}
+
+// linkedPages returns the HTML files and feeds written to the output by [Magnanimous.WriteOutput].
+func (mag *Magnanimous) linkedPages(filesMap WebFilesMap, stack *ContextStack) []linkedPage {
+	var pages []linkedPage
+	for file, wf := range filesMap.WebFiles {
+		if wf.NonWritable || wf.Processed == nil {
+			continue
+		}
+		pages = append(pages, linkedPage{targetFile: filepath.ToSlash(targetFileOf("", file, &wf)),
+			source: wf.Processed, links: linkAttributeRegex})
+	}
+	for _, page := range mag.taxonomyPages(filesMap, stack) {
+		pages = append(pages, linkedPage{targetFile: filepath.ToSlash(page.targetFile),
+			source: page.template.Processed, links: linkAttributeRegex})
+	}
+	for _, feed := range mag.Feeds {
+		origin := filepath.Join(mag.SourcesDir, "processed", feed.Dir)
+		if feed.RSS {
+			pages = append(pages, linkedPage{targetFile: path.Join(filepath.ToSlash(feed.Dir), RSSFeedFileName),
+				origin: origin, links: rssLinkRegex})
+		}
+		if feed.Atom {
+			pages = append(pages, linkedPage{targetFile: path.Join(filepath.ToSlash(feed.Dir), AtomFeedFileName),
+				origin: origin, links: linkAttributeRegex})
+		}
+	}
+	return pages
+}
+
+func newLinkChecker(out outputReader, siteURL, baseURL string) *linkChecker {
+	c := linkChecker{out: out, basePath: "/", anchors: make(map[string]map[string]bool)}
+	if u, err := url.Parse(siteURL); err == nil && u.Host != "" {
+		c.site = u.Scheme + "://" + u.Host
+	}
+	if u, err := url.Parse(baseURL); err == nil && baseURL != "" {
+		c.basePath = strings.TrimSuffix(path.Join("/", u.Path), "/") + "/"
+	}
+	return &c
+}
+
+func (c *linkChecker) check(pages []linkedPage) ([]BrokenLink, error) {
+	var broken []BrokenLink
+	for _, p := range pages {
+		if p.source != nil && strings.ToLower(path.Ext(p.targetFile)) != ".html" {
+			continue
+		}
+		page, err := c.out.ReadFile(p.targetFile)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		for _, m := range p.links.FindAllSubmatch(page, -1) {
+			link := html.UnescapeString(string(m[1]) + string(m[2]))
+			if reason := c.checkLink(p.targetFile, link); reason != "" {
+				location := Location{Origin: p.origin}
+				if p.source != nil {
+					location = sourceLocationOf(p.source, link)
+				}
+				broken = append(broken, BrokenLink{Location: location, Link: link, Reason: reason})
+			}
+		}
+	}
+	sort.Slice(broken, func(i, j int) bool {
+		return broken[i].Location.String()+broken[i].Link < broken[j].Location.String()+broken[j].Link
+	})
+	return broken, nil
+}
+
+// checkLink checks a link found in the given file, returning the reason why it is broken, or the empty string
+// if it is not broken or not internal.
+func (c *linkChecker) checkLink(file, link string) string {
+	if c.site != "" && strings.HasPrefix(link, c.site+c.basePath) {
+		link = strings.TrimPrefix(link, c.site)
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return "invalid URL"
+	}
+	if u.Scheme != "" || u.Host != "" || u.Opaque != "" || (u.Path == "" && u.Fragment == "") {
+		return ""
+	}
+	target := file
+	if u.Path != "" {
+		if strings.HasPrefix(u.Path, "/") {
+			if !strings.HasPrefix(u.Path+"/", c.basePath) {
+				return fmt.Sprintf("absolute link does not start with the baseURL (%s)", c.basePath)
+			}
+			target = path.Clean(strings.TrimPrefix(u.Path, c.basePath))
+		} else {
+			target = path.Join(path.Dir(file), u.Path)
+		}
+		if !c.exists(target) {
+			// links to directories refer to their index file
+			target = path.Join(target, "index.html")
+			if !c.exists(target) {
+				return "target does not exist"
+			}
+		}
+	}
+	if u.Fragment != "" && strings.ToLower(path.Ext(target)) == ".html" {
+		anchors, err := c.anchorsOf(target)
+		if err != nil {
+			return err.Error()
+		}
+		if !anchors[u.Fragment] {
+			return "anchor does not exist"
+		}
+	}
+	return ""
+}
+
+// exists returns whether a file exists in the output.
+func (c *linkChecker) exists(file string) bool {
+	if file == ".." || strings.HasPrefix(file, "../") {
+		return false
+	}
+	if _, ok := c.anchors[file]; ok {
+		return true
+	}
+	_, err := c.out.ReadFile(file)
+	return err == nil
+}
+
+// anchorsOf returns the ids and names of the elements of an HTML file, which are the valid link fragments.
+func (c *linkChecker) anchorsOf(file string) (map[string]bool, error) {
+	if anchors, ok := c.anchors[file]; ok {
+		return anchors, nil
+	}
+	page, err := c.out.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	anchors := make(map[string]bool)
+	for _, m := range anchorAttributeRegex.FindAllSubmatch(page, -1) {
+		anchors[html.UnescapeString(string(m[1])+string(m[2]))] = true
+	}
+	c.anchors[file] = anchors
+	return anchors, nil
+}
+
+// sourceLocationOf returns the location of the first occurrence of a link in a source file, or the location
+// of the file itself if the link cannot be found in it (e.g. because it was generated from an expression).
+func sourceLocationOf(file *ProcessedFile, link string) Location {
+	location := Location{Origin: file.Path}
+	source, err := file.GetRawContents()
+	if err != nil {
+		return location
+	}
+	i := strings.Index(*source, link)
+	if i < 0 && strings.Contains(link, ".html") {
+		// links to Markdown files are rewritten to link to the generated HTML files
+		i = strings.Index(*source, strings.Replace(link, ".html", ".md", 1))
+	}
+	if i < 0 {
+		return location
+	}
+	before := (*source)[:i]
+	location.Row = uint32(strings.Count(before, "\n") + 1)
+	location.Col = uint32(i - strings.LastIndex(before, "\n"))
+	return location
+}
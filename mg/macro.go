@@ -0,0 +1,129 @@
+package mg
+
+import (
+	"fmt"
+	"go/token"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/renatoathaydes/magnanimous/mg/expression"
+)
+
+// MacroContent is the macro instruction, which defines a function that writes the body of the instruction
+// with its parameters bound to the arguments it is called with, as in:
+//
+//	{{ macro badge(text, color) }}<span class="badge {{ eval color }}">{{ eval text }}</span>{{ end }}
+//
+// The macro can then be called from any expression, as in {{ eval badge("new", "green") }}.
+type MacroContent struct {
+	UnscopedContent
+	Name     string
+	Params   []string
+	Text     string
+	Location *Location
+	contents []Content
+}
+
+var _ Content = (*MacroContent)(nil)
+var _ ContentContainer = (*MacroContent)(nil)
+var _ Definition = (*MacroContent)(nil)
+
+// macroCall is a Content that gets written when a macro is called.
+type macroCall struct {
+	name     string
+	location *Location
+	params   []string
+	args     []interface{}
+	contents []Content
+}
+
+var _ Content = (*macroCall)(nil)
+
+// macroDepthContextName is the name of the variable holding the number of nested macro calls being written.
+const macroDepthContextName = "__macro_depth__"
+
+// MaxMacroCallDepth is the maximum number of nested macro calls, which stops macros that call themselves forever.
+const MaxMacroCallDepth = 100
+
+func NewMacroInstruction(arg string, location *Location, original string) Content {
+	arg = strings.TrimSpace(arg)
+	start, end := strings.Index(arg, "("), strings.LastIndex(arg, ")")
+	if start < 0 || end != len(arg)-1 || !token.IsIdentifier(strings.TrimSpace(arg[:start])) {
+		log.Printf("WARNING: (%s) malformed macro instruction (expected name(arg1, arg2...)): %s",
+			location.String(), arg)
+		return unevaluatedExpression(original, location)
+	}
+	macro := MacroContent{Name: strings.TrimSpace(arg[:start]), Text: original, Location: location}
+	if params := strings.TrimSpace(arg[start+1 : end]); params != "" {
+		for _, param := range strings.Split(params, ",") {
+			param = strings.TrimSpace(param)
+			if !token.IsIdentifier(param) {
+				log.Printf("WARNING: (%s) invalid macro parameter name: '%s'", location.String(), param)
+				return unevaluatedExpression(original, location)
+			}
+			macro.Params = append(macro.Params, param)
+		}
+	}
+	return &macro
+}
+
+func (m *MacroContent) GetName() string {
+	return m.Name
+}
+
+func (m *MacroContent) AppendContent(content Content) {
+	m.contents = append(m.contents, content)
+}
+
+func (m *MacroContent) GetLocation() *Location {
+	return m.Location
+}
+
+func (m *MacroContent) Write(writer io.Writer, context Context) ([]Content, error) {
+	if v, ok := m.Eval(context); ok {
+		context.Set(m.Name, v)
+		return nil, nil
+	}
+	return unevaluatedExpressions(m.Text, m.Location), nil
+}
+
+func (m *MacroContent) Eval(context Context) (interface{}, bool) {
+	return expression.Function(m.call), true
+}
+
+func (m *MacroContent) call(args []interface{}) (interface{}, error) {
+	if len(args) != len(m.Params) {
+		return nil, fmt.Errorf("macro %s expects %d argument(s) (%s), got %d",
+			m.Name, len(m.Params), strings.Join(m.Params, ", "), len(args))
+	}
+	return &macroCall{name: m.Name, location: m.Location, params: m.Params, args: args, contents: m.contents}, nil
+}
+
+func (m *MacroContent) String() string {
+	return fmt.Sprintf("MacroContent{%s}", m.Text)
+}
+
+func (c *macroCall) GetLocation() *Location {
+	return c.location
+}
+
+func (c *macroCall) IsScoped() bool {
+	return true
+}
+
+func (c *macroCall) Write(writer io.Writer, context Context) ([]Content, error) {
+	depth := 0
+	if v, ok := context.Get(macroDepthContextName); ok {
+		depth = v.(int)
+	}
+	if depth >= MaxMacroCallDepth {
+		return nil, NewError(*c.location, InclusionCycleError,
+			fmt.Sprintf("Macro %s exceeded the maximum call depth (%d)", c.name, MaxMacroCallDepth))
+	}
+	context.Set(macroDepthContextName, depth+1)
+	for i, param := range c.params {
+		context.Set(param, c.args[i])
+	}
+	return c.contents, nil
+}
@@ -3,11 +3,15 @@ package mg
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"io"
+	"io/fs"
 	"log"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -17,7 +21,13 @@ func (mag *Magnanimous) ReadAll() (WebFilesMap, error) {
 	processedDir := filepath.Join(mag.SourcesDir, "processed")
 	staticDir := filepath.Join(mag.SourcesDir, "static")
 
-	procFiles, staticFiles, otherFiles := collectFiles(mag.SourcesDir, processedDir, staticDir)
+	fsys := sourceFS(mag.Source)
+	procFiles, staticFiles, otherFiles := collectFiles(fsys, mag.SourcesDir, processedDir, staticDir)
+	if mag.Sandbox {
+		if err := mag.checkSandbox(procFiles, staticFiles, otherFiles); err != nil {
+			return WebFilesMap{}, err
+		}
+	}
 	webFiles := WebFilesMap{
 		WebFiles: make(map[string]WebFile, len(procFiles)+len(staticFiles)+len(otherFiles)),
 	}
@@ -26,19 +36,27 @@ func (mag *Magnanimous) ReadAll() (WebFilesMap, error) {
 	if err != nil {
 		return webFiles, err
 	}
-	err = CopyAll(&staticFiles, staticDir, webFiles)
+	err = copyAll(fsys, staticFiles, staticDir, webFiles, true)
 	if err != nil {
 		return webFiles, err
 	}
-	err = AddNonWritables(&otherFiles, mag.SourcesDir, webFiles)
+	err = copyAll(fsys, otherFiles, mag.SourcesDir, webFiles, false)
+	if err == nil && mag.GitDates {
+		if isOSFS(mag.Source) {
+			mag.applyGitDates(webFiles)
+		} else {
+			log.Println("WARNING: git dates can only be used with sources in the file system, " +
+				"using file modification times instead")
+		}
+	}
 	return webFiles, err
 }
 
 // ProcessAll given files, putting the results in the given webFiles map.
 func (mag *Magnanimous) ProcessAll(files []string, basePath string, webFiles *WebFilesMap) error {
-	resolver := DefaultFileResolver{BasePath: mag.SourcesDir, Files: webFiles}
+	resolver := mag.newResolver(webFiles)
 	for _, file := range files {
-		wf, err := ProcessFile(file, basePath, &resolver)
+		wf, err := processFile(sourceFS(mag.Source), file, basePath, &resolver)
 		if err != nil {
 			return err
 		}
@@ -47,12 +65,22 @@ func (mag *Magnanimous) ProcessAll(files []string, basePath string, webFiles *We
 	return nil
 }
 
+// newResolver creates the resolver of the source files in the given map.
+func (mag *Magnanimous) newResolver(webFiles *WebFilesMap) DefaultFileResolver {
+	return DefaultFileResolver{BasePath: mag.SourcesDir, Files: webFiles, Sandboxed: mag.Sandbox, source: mag.Source}
+}
+
 // ProcessFile processes the given file.
 func ProcessFile(file, basePath string, resolver FileResolver) (*WebFile, error) {
-	f, err := os.Open(file)
+	return processFile(osFS{}, file, basePath, resolver)
+}
+
+func processFile(fsys fs.FS, file, basePath string, resolver FileResolver) (*WebFile, error) {
+	f, err := fsys.Open(filepath.ToSlash(file))
 	if err != nil {
 		return nil, err
 	}
+	defer f.Close()
 	reader := bufio.NewReader(f)
 	s, err := f.Stat()
 	if err != nil {
@@ -62,6 +90,8 @@ func ProcessFile(file, basePath string, resolver FileResolver) (*WebFile, error)
 	if err != nil {
 		return nil, err
 	}
+	processed.Size = s.Size()
+	processed.source = fsys
 
 	nonWritable := strings.HasPrefix(filepath.Base(file), "_")
 	return &WebFile{BasePath: basePath, Name: filepath.Base(file), Processed: processed, NonWritable: nonWritable}, nil
@@ -73,21 +103,60 @@ func ProcessReader(reader *bufio.Reader, file, basePath string, sizeHint int, re
 
 	var builder strings.Builder
 	builder.Grow(sizeHint)
-	processed := ProcessedFile{BasePath: basePath, Path: file, LastUpdated: lastUpdated}
+	processed := ProcessedFile{BasePath: basePath, Path: file, LastUpdated: lastUpdated, resolver: resolver}
 	if isMd(file) {
 		processed.NewExtension = "html"
 	}
 	stack := []ContentContainer{&processed}
 	state := parserState{file: file, row: 1, col: 1, builder: &builder, reader: reader, contentStack: stack}
-	magErr := parseText(&state, resolver)
+	if isHtml(file) {
+		state.html = &strings.Builder{}
+	}
+	frontMatter, magErr := parseFrontMatter(&state)
+	if magErr != nil {
+		return &processed, magErr
+	}
+	if frontMatter != nil {
+		state.append(frontMatter)
+	}
+	magErr = parseText(&state, resolver)
 	if magErr != nil {
 		return &processed, magErr
 	}
 	return &processed, nil
 }
 
-func (mag *Magnanimous) newContextStack(filesMap WebFilesMap) ContextStack {
-	var stack = NewContextStack(NewContext())
+// ProfileContextName is the name of the variable holding the active profile, if any.
+const ProfileContextName = "profile"
+
+// newContextStack creates the global context all files are written with.
+//
+// Variables are layered with the following precedence order, from lowest to highest:
+//
+//  1. data files (the data variable) and the env variable (unless in Sandbox mode).
+//  2. the global context file.
+//  3. the profile's global context file (the global context file name plus "." + profile name).
+//  4. the Definitions (e.g. given in the command-line).
+func (mag *Magnanimous) newContextStack(filesMap WebFilesMap) (ContextStack, error) {
+	overrides := NewContext()
+	for name, value := range mag.Definitions {
+		overrides.Set(name, definitionValue(value))
+	}
+	var stack = NewContextStack(&overridingContext{values: NewContext(), overrides: overrides})
+	if !mag.Sandbox {
+		// untrusted sources must not read secrets from the environment
+		stack.Set(EnvContextName, envContext{})
+	}
+	data, err := loadData(sourceFS(mag.Source), filepath.Join(mag.SourcesDir, "data"), filesMap)
+	if err != nil {
+		return stack, err
+	}
+	if len(data) > 0 {
+		stack.Set(DataContextName, data)
+	}
+	if mag.Profile != "" {
+		stack.Set(ProfileContextName, mag.Profile)
+	}
 	var globalCtxPath string
 	if mag.GlobalContex != "" {
 		globalCtxPath = path.Join(mag.SourcesDir, "processed", mag.GlobalContex)
@@ -99,49 +168,101 @@ func (mag *Magnanimous) newContextStack(filesMap WebFilesMap) ContextStack {
 		globalCtx.Processed.ResolveContext(&stack, true)
 	} else if mag.GlobalContex != "" {
 		log.Printf("WARNING: global context file was not found: %s", globalCtxPath)
-	} else {
+	} else if mag.Profile == "" {
 		log.Println("No global context file defined.")
 	}
-	return stack
+	if mag.Profile != "" {
+		profileCtxPath := globalCtxPath + "." + mag.Profile
+		if profileCtx, ok := filesMap.WebFiles[profileCtxPath]; ok {
+			log.Printf("Using global context file for profile '%s': %s", mag.Profile, profileCtxPath)
+			profileCtx.Processed.ResolveContext(&stack, true)
+		} else {
+			log.Printf("WARNING: global context file for profile '%s' was not found: %s",
+				mag.Profile, profileCtxPath)
+		}
+	}
+	return stack, nil
+}
+
+// numberLiteralRegex matches the number literals of definitions.
+var numberLiteralRegex = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// definitionValue converts the value of a definition (see [Magnanimous.Definitions]) to a boolean or number
+// if it is a literal of that type.
+func definitionValue(value string) interface{} {
+	switch {
+	case value == "true":
+		return true
+	case value == "false":
+		return false
+	case numberLiteralRegex.MatchString(value):
+		// numbers that would not be written back as given (e.g. 1.0) are kept as strings, as in versions
+		if n, err := strconv.ParseFloat(value, 64); err == nil && strconv.FormatFloat(n, 'f', -1, 64) == value {
+			return n
+		}
+	case len(value) > 1 && strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\""):
+		return value[1 : len(value)-1]
+	}
+	return value
 }
 
 // WriteTo writes all files in the given map on the given directory.
 func (mag *Magnanimous) WriteTo(dir string, filesMap WebFilesMap) error {
-	stack := mag.newContextStack(filesMap)
-
 	err := os.MkdirAll(dir, 0770)
 	if err != nil {
 		return &MagnanimousError{Code: IOError, message: err.Error()}
 	}
+	return mag.WriteOutput(DirOutput(dir), filesMap)
+}
+
+// WriteOutput writes all files in the given map to the given output.
+func (mag *Magnanimous) WriteOutput(out Output, filesMap WebFilesMap) error {
+	stack, err := mag.newContextStack(filesMap)
+	if err != nil {
+		return err
+	}
+	mag.unpublish(filesMap, &stack, time.Now())
+
 	for file, wf := range filesMap.WebFiles {
 		if wf.NonWritable {
 			continue
 		}
-		targetPath, err := filepath.Rel(wf.BasePath, file)
-		if err != nil {
-			log.Printf("Unable to relativize path %s", file)
-			targetPath = file
-		}
-		targetFile := filepath.Join(dir, targetPath)
-		if wf.Processed.NewExtension != "" {
-			targetFile = changeFileExt(targetFile, wf.Processed.NewExtension)
-		}
-		magErr := writeFile(file, targetFile, wf, &stack)
+		magErr := writeFile(out, file, targetFileOf("", file, &wf), wf, &stack)
 		if magErr != nil {
 			return magErr
 		}
 	}
-	return nil
+	err = mag.writeTaxonomies(out, filesMap, &stack)
+	if err == nil && mag.Sitemap {
+		err = mag.writeSitemap(out, filesMap, &stack)
+	}
+	if err == nil {
+		err = mag.writeFeeds(out, filesMap, &stack)
+	}
+	if err == nil {
+		err = mag.writeCodeStylesheets(out)
+	}
+	return err
 }
 
-func writeFile(file, targetFile string, wf WebFile, stack *ContextStack) error {
-	err := os.MkdirAll(filepath.Dir(targetFile), 0770)
+// targetFileOf returns the path of the file the given source file is written to within the target directory.
+func targetFileOf(dir, file string, wf *WebFile) string {
+	targetPath, err := filepath.Rel(wf.BasePath, file)
 	if err != nil {
-		return err
+		log.Printf("Unable to relativize path %s", file)
+		targetPath = file
 	}
+	targetFile := filepath.Join(dir, targetPath)
+	if wf.Processed.NewExtension != "" {
+		targetFile = changeFileExt(targetFile, wf.Processed.NewExtension)
+	}
+	return targetFile
+}
 
+// writeFile writes a file to the given output, where targetFile is the path of the file within the output.
+func writeFile(out Output, file, targetFile string, wf WebFile, stack *ContextStack) error {
 	if wf.SkipIfUpToDate {
-		upToDate, err := isUpToDate(&wf, targetFile)
+		upToDate, err := isUpToDate(&wf, out, targetFile)
 		if err != nil {
 			return err
 		}
@@ -152,15 +273,9 @@ func writeFile(file, targetFile string, wf WebFile, stack *ContextStack) error {
 	}
 
 	log.Printf("Creating file %s from %s", targetFile, file)
-	f, err := os.Create(targetFile)
-	if err != nil {
-		return err
-	}
-
-	defer f.Close()
-	w := bufio.NewWriter(f)
-	defer w.Flush()
-	return wf.Write(w, stack, true, false)
+	return writeOutputFile(out, targetFile, func(w io.Writer) error {
+		return wf.Write(w, stack, true, false)
+	})
 }
 
 func (wf *WebFile) Write(writer io.Writer, stack *ContextStack, useScope, writePlain bool) error {
@@ -172,14 +287,20 @@ func (wf *WebFile) Write(writer io.Writer, stack *ContextStack, useScope, writeP
 	inMd := isMd(wf.Processed.GetLocation().Origin)
 	var buffer bytes.Buffer
 	buffer.Grow(512)
-	inMd, err := writeContents(wf.Processed.GetContents(), writer, &buffer, stack, inMd, writePlain)
+	// the whole page is written before being written out so that the table of contents can be inserted
+	var page bytes.Buffer
+	links := &mdLinks{resolver: wf.Processed.resolver, page: &Location{Origin: wf.Processed.Path}}
+	inMd, err := writeContents(wf.Processed.GetContents(), &page, &buffer, stack, links, inMd, writePlain)
 	if err == nil {
 		if inMd && !writePlain {
-			err = flushMdAsHtml(&buffer, writer)
+			err = flushMdAsHtml(&buffer, &page, stack, links)
 		} else {
-			err = flush(&buffer, writer)
+			err = flush(&buffer, &page)
 		}
 	}
+	if err == nil {
+		_, err = writer.Write(insertToc(page.Bytes()))
+	}
 	return err
 }
 
@@ -189,10 +310,10 @@ func (wf *WebFile) GetLocation() *Location {
 }
 
 func writeContents(contents []Content, writer io.Writer, buffer *bytes.Buffer, stack *ContextStack,
-	inMd, writePlain bool) (stillInMd bool, err error) {
+	links *mdLinks, inMd, writePlain bool) (stillInMd bool, err error) {
 	stillInMd = inMd
 	for _, content := range contents {
-		stillInMd, err = writeContent(content, writer, buffer, stack, stillInMd, writePlain)
+		stillInMd, err = writeContent(content, writer, buffer, stack, links, stillInMd, writePlain)
 		if err != nil {
 			return
 		}
@@ -206,7 +327,7 @@ func forceMarkdown(stack *ContextStack) bool {
 }
 
 func writeContent(c Content, writer io.Writer, buffer *bytes.Buffer, stack *ContextStack,
-	inMd, writePlain bool) (stillInMd bool, err error) {
+	links *mdLinks, inMd, writePlain bool) (stillInMd bool, err error) {
 	forceMd := forceMarkdown(stack)
 	isScoped := c.IsScoped()
 	stillInMd = forceMd || isMd(c.GetLocation().Origin)
@@ -214,7 +335,7 @@ func writeContent(c Content, writer io.Writer, buffer *bytes.Buffer, stack *Cont
 	if writePlain || (!inMd && stillInMd) {
 		err = flush(buffer, writer)
 	} else if inMd && !stillInMd {
-		err = flushMdAsHtml(buffer, writer)
+		err = flushMdAsHtml(buffer, writer, stack, links)
 	}
 	if err != nil {
 		return
@@ -225,7 +346,7 @@ func writeContent(c Content, writer io.Writer, buffer *bytes.Buffer, stack *Cont
 
 	next, err := c.Write(buffer, stack)
 	if err == nil && len(next) > 0 {
-		stillInMd, err = writeContents(next, writer, buffer, stack, stillInMd || forceMd, writePlain)
+		stillInMd, err = writeContents(next, writer, buffer, stack, links, stillInMd || forceMd, writePlain)
 	}
 	return
 }
@@ -240,13 +361,27 @@ func flush(buffer *bytes.Buffer, writer io.Writer) error {
 	return err
 }
 
-func isUpToDate(wf *WebFile, targetFile string) (bool, error) {
-	stat, err := os.Stat(targetFile)
+// isUpToDate checks whether the target file was modified after the source file.
+//
+// The modification time of the source file is always used, as its LastUpdated time may come from
+// the git history, which does not reflect when the file was last written.
+//
+// Files are never up-to-date in outputs that cannot tell when a file was last written.
+func isUpToDate(wf *WebFile, out Output, targetFile string) (bool, error) {
+	stater, ok := out.(outputStater)
+	if !ok {
+		return false, nil
+	}
+	stat, err := stater.Stat(filepath.ToSlash(targetFile))
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			return false, nil
 		}
 		return false, &MagnanimousError{Code: IOError, message: err.Error()}
 	}
-	return stat.ModTime().After(wf.Processed.LastUpdated), nil
+	sourceStat, err := fs.Stat(sourceFS(wf.Processed.source), filepath.ToSlash(wf.Processed.Path))
+	if err != nil {
+		return false, &MagnanimousError{Code: IOError, message: err.Error()}
+	}
+	return stat.ModTime().After(sourceStat.ModTime()), nil
 }
@@ -2,7 +2,10 @@ package mg
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+
+	"github.com/renatoathaydes/magnanimous/mg/expression"
 )
 
 type mapContext struct {
@@ -23,28 +26,37 @@ func (m *mapContext) ToStack() *ContextStack {
 	return &stack
 }
 
-// newFileContext creates a simple Context that, when evaluated, resolves to a file path.
+// newFileContext creates a Context that, when evaluated, resolves to a file path.
 // This allows for-loops to evaluate all file paths in a directory.
+//
+// The Context also provides the properties computed from the file's contents (see [fileContext]).
 func newFileContext(file *ProcessedFile, base Context) Context {
 	var str string
 	if file != nil {
-		path := file.Path
-		if file.NewExtension != "" {
-			path = changeFileExt(path, file.NewExtension)
-		}
-		// try to figure out a valid link to the file
-		s, err := filepath.Rel(file.BasePath, path)
-		if err == nil {
-			str = "/" + s
-		} else {
-			str = path
-		}
+		str = fileLink(file)
 	}
 	stack := NewContextStack(base)
-	stack.push(&mapContext{ctx: make(map[string]interface{}, 10), str: &str})
+	stack.push(&fileContext{
+		mapContext: mapContext{ctx: make(map[string]interface{}, 10), str: &str},
+		file:       file,
+		base:       base,
+	})
 	return &stack
 }
 
+// fileLink tries to figure out a valid link to the file, relative to the root of the website.
+func fileLink(file *ProcessedFile) string {
+	path := file.Path
+	if file.NewExtension != "" {
+		path = changeFileExt(path, file.NewExtension)
+	}
+	s, err := filepath.Rel(file.BasePath, path)
+	if err == nil {
+		return "/" + s
+	}
+	return path
+}
+
 func (m *mapContext) Get(name string) (interface{}, bool) {
 	v, ok := m.ctx[name]
 	return v, ok
@@ -70,3 +82,63 @@ func (m *mapContext) String() string {
 	}
 	return fmt.Sprint(m.ctx)
 }
+
+// overridingContext is a Context whose overrides always take precedence over its own values.
+//
+// Values set into an overridingContext are never set into the overrides, so definitions that
+// depend on an overridden value see the override (e.g. a value given in the command-line), but cannot
+// modify it.
+type overridingContext struct {
+	values    Context
+	overrides Context
+}
+
+var _ Context = (*overridingContext)(nil)
+
+func (o *overridingContext) Get(name string) (interface{}, bool) {
+	if v, ok := o.overrides.Get(name); ok {
+		return v, true
+	}
+	return o.values.Get(name)
+}
+
+func (o *overridingContext) Set(name string, value interface{}) {
+	o.values.Set(name, value)
+}
+
+func (o *overridingContext) Remove(name string) interface{} {
+	return o.values.Remove(name)
+}
+
+func (o *overridingContext) IsEmpty() bool {
+	return o.values.IsEmpty() && o.overrides.IsEmpty()
+}
+
+func (o *overridingContext) ToStack() *ContextStack {
+	stack := NewContextStack(o)
+	return &stack
+}
+
+func (o *overridingContext) String() string {
+	return fmt.Sprintf("%v (overrides: %v)", o.values, o.overrides)
+}
+
+// EnvContextName is the name of the variable giving access to environment variables, as in env["HOME"].
+const EnvContextName = "env"
+
+// envContext is an expression context that resolves variables from the environment.
+type envContext struct{}
+
+var _ expression.Context = envContext{}
+
+// Get the value of the environment variable with the given name.
+func (envContext) Get(name string) (interface{}, bool) {
+	if v, ok := os.LookupEnv(name); ok {
+		return v, true
+	}
+	return nil, false
+}
+
+func (envContext) String() string {
+	return "env"
+}
@@ -2,31 +2,190 @@ package mg
 
 import (
 	"bytes"
+	"fmt"
 	"io"
+	"log"
+	"regexp"
+	"strings"
 
 	"github.com/Depado/bfchroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/styles"
 	"github.com/russross/blackfriday/v2"
 )
 
 var mdStyle = bfchroma.Style("lovelace")
 
+var mdClasses = false
+
+// MarkdownContextName is the name of the variable holding the Markdown extensions to enable (or, if prefixed
+// with "-", disable), either as a list or as a comma-separated string, e.g. "footnotes, -smartypants".
+const MarkdownContextName = "markdownExtensions"
+
+type markdownExtension struct {
+	extensions blackfriday.Extensions
+	flags      blackfriday.HTMLFlags
+}
+
+// markdownExtensions are the Markdown extensions that can be enabled or disabled via the markdownExtensions
+// variable.
+var markdownExtensions = map[string]markdownExtension{
+	"headingIds":      {extensions: blackfriday.HeadingIDs},
+	"autoHeadingIds":  {extensions: blackfriday.AutoHeadingIDs},
+	"footnotes":       {extensions: blackfriday.Footnotes, flags: blackfriday.FootnoteReturnLinks},
+	"definitionLists": {extensions: blackfriday.DefinitionLists},
+	"tables":          {extensions: blackfriday.Tables},
+	"hardLineBreaks":  {extensions: blackfriday.HardLineBreak},
+	"smartypants": {flags: blackfriday.Smartypants | blackfriday.SmartypantsFractions |
+		blackfriday.SmartypantsDashes | blackfriday.SmartypantsLatexDashes},
+	"safe": {flags: blackfriday.SkipHTML | blackfriday.Safelink},
+}
+
 // SetCodeStyle sets the code style used to highlight source code.
 // See https://xyproto.github.io/splash/docs/all.html for the supported styles.
 func SetCodeStyle(style string) {
 	mdStyle = bfchroma.Style(style)
 }
 
-func flushMdAsHtml(buffer *bytes.Buffer, writer io.Writer) error {
+// SetCodeClasses sets whether highlighted source code uses CSS classes instead of inline styles.
+//
+// When using CSS classes, a stylesheet for the code style must be included in the website
+// (see [Magnanimous.CodeStylesheets]).
+func SetCodeClasses(classes bool) {
+	mdClasses = classes
+}
+
+// codeStylesheetFileName returns the name of the stylesheet file of a code style.
+func codeStylesheetFileName(style string) string {
+	return "chroma-" + style + ".css"
+}
+
+// codeStyleClass returns the name of the class an element must have for the code style to apply to the code
+// blocks within it, unless it is the default code style.
+func codeStyleClass(style string) string {
+	return "chroma-" + style
+}
+
+// cssRuleRegex matches the rules written by chroma, as in "/* Keyword */ .chroma .k { color: #000 }",
+// capturing the comment and the selector.
+var cssRuleRegex = regexp.MustCompile(`(?m)^(/\*[^*]*\*/ )([^{\n]+ \{)`)
+
+// writeCodeStylesheets writes the stylesheets of each of the code styles in [Magnanimous.CodeStylesheets]
+// to the given output.
+//
+// All code styles use the same CSS classes, so only the first style applies everywhere. The rules of the others
+// are scoped by the class returned by [codeStyleClass], so they only apply within an element with that class.
+func (mag *Magnanimous) writeCodeStylesheets(out Output) error {
+	formatter := html.New(html.WithClasses(true))
+	for i, name := range mag.CodeStylesheets {
+		style, ok := styles.Registry[name]
+		if !ok {
+			return &MagnanimousError{Code: ParseError, message: fmt.Sprintf("unknown code style: %s", name)}
+		}
+		targetFile := codeStylesheetFileName(name)
+		log.Printf("Creating code stylesheet %s", targetFile)
+		err := writeOutputFile(out, targetFile, func(w io.Writer) error {
+			if i == 0 {
+				return formatter.WriteCSS(w, style)
+			}
+			var css bytes.Buffer
+			if err := formatter.WriteCSS(&css, style); err != nil {
+				return err
+			}
+			_, err := w.Write(cssRuleRegex.ReplaceAll(css.Bytes(), []byte("${1}."+codeStyleClass(name)+" $2")))
+			return err
+		})
+		if err != nil {
+			return &MagnanimousError{Code: IOError, message: err.Error()}
+		}
+	}
+	return nil
+}
+
+func flushMdAsHtml(buffer *bytes.Buffer, writer io.Writer, context Context, links *mdLinks) error {
 	defer buffer.Reset()
 	mdBytes := buffer.Bytes()
 	if len(mdBytes) == 0 {
 		return nil
 	}
-	var chromaRenderer = blackfriday.WithRenderer(
-		bfchroma.NewRenderer(bfchroma.WithoutAutodetect(), mdStyle))
+	extensions, flags := markdownOptions(context)
+	var chromaRenderer = blackfriday.WithRenderer(&markdownRenderer{
+		Renderer: bfchroma.NewRenderer(bfchroma.WithoutAutodetect(), mdStyle,
+			bfchroma.ChromaOptions(html.WithClasses(mdClasses)),
+			bfchroma.Extend(blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{Flags: flags}))),
+		links: links,
+	})
 
-	html := blackfriday.Run(mdBytes, chromaRenderer)
+	result := blackfriday.Run(mdBytes, chromaRenderer, blackfriday.WithExtensions(extensions))
 
-	_, err := writer.Write(html)
+	_, err := writer.Write(result)
 	return err
 }
+
+// markdownRenderer is the blackfriday renderer used by Magnanimous.
+//
+// It highlights code blocks with chroma (see [markdownRenderer.renderCodeBlock]) and rewrites links to
+// Markdown files so that they point to the generated HTML files.
+type markdownRenderer struct {
+	*bfchroma.Renderer
+	links *mdLinks
+}
+
+func (r *markdownRenderer) RenderNode(w io.Writer, node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+	switch node.Type {
+	case blackfriday.CodeBlock:
+		return r.renderCodeBlock(w, node, entering)
+	case blackfriday.Link:
+		if entering {
+			r.links.rewrite(node)
+		}
+	case blackfriday.HTMLBlock, blackfriday.HTMLSpan:
+		// the toc placeholder must be kept even if HTML is skipped (see the "safe" extension)
+		if base, ok := r.Base.(*blackfriday.HTMLRenderer); ok && tocPlaceholderOnlyRegex.Match(node.Literal) {
+			flags := base.Flags
+			base.Flags &^= blackfriday.SkipHTML
+			defer func() { base.Flags = flags }()
+		}
+	}
+	return r.Renderer.RenderNode(w, node, entering)
+}
+
+// markdownOptions returns the blackfriday extensions and HTML flags to use, starting from blackfriday's
+// defaults and applying the changes given by the markdownExtensions variable, if any.
+func markdownOptions(context Context) (blackfriday.Extensions, blackfriday.HTMLFlags) {
+	extensions, flags := blackfriday.CommonExtensions, blackfriday.CommonHTMLFlags
+	v, ok := context.Get(MarkdownContextName)
+	if !ok || v == nil {
+		return extensions, flags
+	}
+	var names []string
+	switch value := v.(type) {
+	case []interface{}:
+		for _, name := range value {
+			names = append(names, fmt.Sprint(name))
+		}
+	default:
+		names = strings.Split(fmt.Sprint(value), ",")
+	}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		disable := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+		if name == "" {
+			continue
+		}
+		ext, ok := markdownExtensions[name]
+		if !ok {
+			log.Printf("WARNING: ignoring unknown Markdown extension in %s variable: %s", MarkdownContextName, name)
+			continue
+		}
+		if disable {
+			extensions &^= ext.extensions
+			flags &^= ext.flags
+		} else {
+			extensions |= ext.extensions
+			flags |= ext.flags
+		}
+	}
+	return extensions, flags
+}
@@ -0,0 +1,45 @@
+package mg
+
+import (
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+// mdLinks rewrites links to Markdown files found in Markdown content so that they point to the files
+// generated from them, e.g. [other page](other.md) becomes a link to other.html.
+//
+// Absolute links are resolved from the processed directory, and relative links from the page being written.
+type mdLinks struct {
+	resolver FileResolver
+	page     *Location
+}
+
+// rewrite rewrites the destination of the given link node, if it refers to a Markdown file.
+//
+// Links to external resources or to non-Markdown files are left unchanged, as are links to Markdown files
+// that cannot be found (in which case a warning is logged).
+func (l *mdLinks) rewrite(node *blackfriday.Node) {
+	if l == nil || l.resolver == nil || l.page == nil {
+		return
+	}
+	link, err := url.Parse(string(node.LinkData.Destination))
+	if err != nil || link.Scheme != "" || link.Host != "" || link.Opaque != "" ||
+		!strings.HasSuffix(strings.ToLower(link.Path), ".md") {
+		return
+	}
+	target := link.Path
+	if strings.HasPrefix(target, "/") {
+		target = "/processed" + target
+	}
+	wf, ok := l.resolver.Get(l.resolver.Resolve(target, l.page, nil))
+	if !ok || wf.Processed == nil || wf.Processed.NewExtension == "" {
+		log.Printf("WARNING: (%s) link refers to a Markdown file that does not exist: %s",
+			l.page.String(), link.Path)
+		return
+	}
+	link.Path = changeFileExt(link.Path, wf.Processed.NewExtension)
+	node.LinkData.Destination = []byte(link.String())
+}
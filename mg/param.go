@@ -0,0 +1,199 @@
+package mg
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/renatoathaydes/magnanimous/mg/expression"
+)
+
+// ParamInstruction is the param instruction, which declares a parameter of a component.
+//
+// Parameters are declared at the top level of a component file, as in {{ param kind:string "info" }}, where
+// the optional type follows the name (see [ParamTypes]) and the optional expression after it is the default
+// value of the parameter.
+//
+// Parameters without a default value are required, unless their name ends with '?', as in
+// {{ param subtitle?:string }}: using the component without giving a value for a required parameter is an error.
+type ParamInstruction struct {
+	UnscopedContent
+	Name     string
+	Type     string
+	Required bool
+	Text     string
+	Default  *expression.Expression
+	Location *Location
+}
+
+var _ Content = (*ParamInstruction)(nil)
+
+// ParamTypes are the types a component parameter may declare, mapped to the function checking whether a value
+// has the type.
+var ParamTypes = map[string]func(v interface{}) bool{
+	"string": func(v interface{}) bool {
+		switch v.(type) {
+		case string, *slotEval:
+			return true
+		}
+		return false
+	},
+	"number": func(v interface{}) bool {
+		switch v.(type) {
+		case float64, int:
+			return true
+		}
+		return false
+	},
+	"boolean": func(v interface{}) bool {
+		_, ok := v.(bool)
+		return ok
+	},
+	"list": func(v interface{}) bool {
+		_, ok := v.([]interface{})
+		return ok
+	},
+	"date": func(v interface{}) bool {
+		_, ok := v.(*expression.DateTime)
+		return ok
+	},
+	"object": func(v interface{}) bool {
+		switch v.(type) {
+		case expression.Context, map[string]interface{}:
+			return true
+		}
+		return false
+	},
+}
+
+func NewParamInstruction(arg string, location *Location, original string) Content {
+	parts := strings.SplitN(strings.TrimSpace(arg), " ", 2)
+	name, typ := parts[0], ""
+	if i := strings.Index(name, ":"); i >= 0 {
+		name, typ = name[:i], name[i+1:]
+	}
+	optional := strings.HasSuffix(name, "?")
+	name = strings.TrimSuffix(name, "?")
+	if _, ok := ParamTypes[typ]; name == "" || (typ != "" && !ok) {
+		log.Printf("WARNING: (%s) malformed param instruction (type must be one of %s): %s",
+			location.String(), paramTypeNames(), arg)
+		return unevaluatedExpression(original, location)
+	}
+	param := ParamInstruction{Name: name, Type: typ, Required: !optional, Text: original, Location: location}
+	if len(parts) == 2 {
+		expr, err := expression.ParseExpr(parts[1])
+		if err != nil {
+			log.Printf("WARNING: (%s) Unable to eval (default of parameter %s): %s (%s)",
+				location.String(), param.Name, parts[1], err.Error())
+			return unevaluatedExpression(original, location)
+		}
+		param.Default = &expr
+		param.Required = false
+	}
+	return &param
+}
+
+func paramTypeNames() string {
+	var names []string
+	for name := range ParamTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// typeNameOf returns the name of the parameter type of a value, or its Go type if it has none of those types.
+func typeNameOf(v interface{}) string {
+	for _, name := range []string{"string", "number", "boolean", "list", "date", "object"} {
+		if ParamTypes[name](v) {
+			return name
+		}
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func (p *ParamInstruction) GetLocation() *Location {
+	return p.Location
+}
+
+// Write sets the default value of the parameter if it is not defined, which only happens when the file
+// declaring it is not used as a component (as [Component.Write] binds all parameters).
+func (p *ParamInstruction) Write(writer io.Writer, context Context) ([]Content, error) {
+	if _, ok := context.Get(p.Name); !ok && p.Default != nil {
+		v, err := p.defaultValue(context)
+		if err != nil {
+			return nil, err
+		}
+		context.Set(p.Name, v)
+	}
+	return nil, nil
+}
+
+func (p *ParamInstruction) String() string {
+	return fmt.Sprintf("ParamInstruction{%s}", p.Text)
+}
+
+func (p *ParamInstruction) defaultValue(context Context) (interface{}, error) {
+	v, err := expression.EvalExpr(p.Default, context)
+	if err != nil {
+		return nil, NewError(*p.Location, ParseError,
+			fmt.Sprintf("cannot evaluate default value of parameter '%s': %s", p.Name, err.Error()))
+	}
+	return v, nil
+}
+
+// componentParams returns the parameters declared at the top level of a component's contents.
+func componentParams(contents []Content) []*ParamInstruction {
+	var params []*ParamInstruction
+	for _, c := range contents {
+		if p, ok := c.(*ParamInstruction); ok {
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+func findParam(params []*ParamInstruction, name string) *ParamInstruction {
+	for _, p := range params {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// bindParams binds the parameters of a component in the component's scope, which is expected to be at the
+// top of the context stack, setting the default value of parameters not given by the caller and checking the
+// type of each value.
+//
+// Only the caller's arguments and slots can give a parameter's value, not variables defined outside the
+// component's scope, so that a parameter cannot be accidentally set by an unrelated variable.
+//
+// Errors are reported at the caller's location, unless caused by a parameter's default value.
+func bindParams(params []*ParamInstruction, path string, caller *Location, context Context) error {
+	scope := context.ToStack().Top()
+	for _, p := range params {
+		location := caller
+		v, ok := scope.Get(p.Name)
+		if !ok || v == nil {
+			if p.Default != nil {
+				var err error
+				if v, err = p.defaultValue(context); err != nil {
+					return err
+				}
+				location = p.Location
+			} else if p.Required {
+				return NewError(*caller, ParseError,
+					fmt.Sprintf("component %s requires parameter '%s'", path, p.Name))
+			}
+			context.Set(p.Name, v)
+		}
+		if v != nil && p.Type != "" && !ParamTypes[p.Type](v) {
+			return NewError(*location, ParseError, fmt.Sprintf("component %s parameter '%s' must be a %s, not a %s",
+				path, p.Name, p.Type, typeNameOf(v)))
+		}
+	}
+	return nil
+}
@@ -16,6 +16,9 @@ type parserState struct {
 	//pf           *ProcessedFile
 	builder      *strings.Builder
 	contentStack []ContentContainer
+	// html is the lower-case text of an HTML file parsed so far, used to find out how to escape values,
+	// or nil if the file is not an HTML file.
+	html *strings.Builder
 }
 
 func (state *parserState) append(content Content) {
@@ -47,6 +50,9 @@ begin:
 		// last part, only include content if not only whitespaces
 		includeContent = len(strings.TrimSpace(content)) > 0
 	}
+	if state.html != nil {
+		state.html.WriteString(strings.ToLower(content))
+	}
 	if includeContent {
 		loc := Location{Origin: state.file, Row: state.row, Col: state.col}
 		state.append(NewStringContent(content, &loc))
@@ -87,6 +93,11 @@ func parseInstruction(state *parserState, resolver FileResolver) error {
 }
 
 func appendInstructionContent(state *parserState, text string, location *Location, resolver FileResolver) {
+	if trimmed := strings.TrimSpace(text); strings.HasPrefix(trimmed, "<") {
+		// shortcodes do not require a space after '<'
+		state.append(NewShortcodeInstruction(trimmed[1:], location, text, resolver))
+		return
+	}
 	parts := strings.SplitN(strings.TrimSpace(text), " ", 2)
 	switch len(parts) {
 	case 0:
@@ -98,12 +109,17 @@ func appendInstructionContent(state *parserState, text string, location *Locatio
 				log.Printf("WARNING: (%s) %s", location, "end instruction does not match any open scope")
 				state.append(unevaluatedExpression(text, location))
 			}
+		} else if parts[0] == "toc" {
+			state.append(NewTocInstruction("", location, text))
 		} else {
 			log.Printf("WARNING: (%s) Instruction missing argument: %s", location.String(), text)
 			state.append(unevaluatedExpression(text, location))
 		}
 	case 2:
 		content := createInstruction(parts[0], parts[1], location, text, resolver)
+		if e, ok := content.(escaping); ok && state.html != nil {
+			e.setEscape(htmlEscapeAt(state.html.String()))
+		}
 		if content != nil {
 			state.append(content)
 		}
@@ -133,6 +149,14 @@ func createInstruction(name, arg string, location *Location,
 		return NewComponentInstruction(arg, location, original, resolver)
 	case "slot":
 		return NewSlotInstruction(arg, location, original, resolver)
+	case "slotOr":
+		return NewSlotOrInstruction(arg, location, original, resolver)
+	case "macro":
+		return NewMacroInstruction(arg, location, original)
+	case "param":
+		return NewParamInstruction(arg, location, original)
+	case "toc":
+		return NewTocInstruction(arg, location, original)
 	}
 
 	log.Printf("WARNING: (%s) Unknown instruction: '%s'", location.String(), name)
@@ -3,8 +3,9 @@ package mg
 import (
 	"bytes"
 	"fmt"
+	"io/fs"
 	"log"
-	"os"
+	"path/filepath"
 	"strings"
 	"time"
 )
@@ -16,6 +17,13 @@ type ProcessedFile struct {
 	BasePath     string
 	Path         string
 	LastUpdated  time.Time
+	// Size of the source file, in bytes.
+	Size int64
+	// LastUpdatedBy is the author of the last change to the file, if known.
+	LastUpdatedBy string
+	resolver      FileResolver
+	// source is the file system the file was read from (see [Magnanimous.Source]).
+	source fs.FS
 }
 
 var _ ContentContainer = (*ProcessedFile)(nil)
@@ -36,7 +44,7 @@ func (f *ProcessedFile) GetContents() []Content {
 // GetRawContents re-reads this file without any processing, returning
 // the contents as a single string.
 func (f *ProcessedFile) GetRawContents() (*string, error) {
-	c, err := os.ReadFile(f.Path)
+	c, err := fs.ReadFile(sourceFS(f.source), filepath.ToSlash(f.Path))
 	if err != nil {
 		return nil, err
 	}
@@ -55,6 +63,11 @@ func (f *ProcessedFile) ResolveContext(context Context, inPlace bool) Context {
 		ctx = context
 	} else {
 		ctx = newFileContext(f, context)
+		if fc, ok := ctx.ToStack().Top().(*fileContext); ok {
+			// properties must not shadow variables used in the file's definitions
+			fc.resolving = true
+			defer func() { fc.resolving = false }()
+		}
 	}
 	resolveContext(f.GetContents(), ctx)
 	return ctx
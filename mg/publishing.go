@@ -0,0 +1,86 @@
+package mg
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/renatoathaydes/magnanimous/mg/expression"
+)
+
+const (
+	// DraftContextName is the name of the variable that marks a file as a draft.
+	DraftContextName = "draft"
+	// PublishDateContextName is the name of the variable holding the date from which a file may be published.
+	PublishDateContextName = "publishDate"
+	// ExpiryDateContextName is the name of the variable holding the date after which a file is no longer published.
+	ExpiryDateContextName = "expiryDate"
+	// BuildDraftsContextName is the name of a global variable that, if true, has the same effect as
+	// setting IncludeDrafts, so that profiles can enable it.
+	BuildDraftsContextName = "buildDrafts"
+	// BuildFutureContextName is the name of a global variable that, if true, has the same effect as
+	// setting IncludeFuture, so that profiles can enable it.
+	BuildFutureContextName = "buildFuture"
+)
+
+// unpublish makes all processed files that should not be published at this time non-writable.
+//
+// Files are not published if they are drafts, their publish date is in the future, or their expiry date is in
+// the past. Non-writable files are still available to other files (e.g. to be included), but are not written
+// out and are not returned by [FileResolver.FilesIn].
+func (mag *Magnanimous) unpublish(filesMap WebFilesMap, stack *ContextStack, now time.Time) {
+	includeDrafts := mag.IncludeDrafts || isTrue(stack, BuildDraftsContextName)
+	includeFuture := mag.IncludeFuture || isTrue(stack, BuildFutureContextName)
+	processedDir := filepath.Join(mag.SourcesDir, "processed")
+
+	for file, wf := range filesMap.WebFiles {
+		if wf.NonWritable || wf.BasePath != processedDir {
+			continue
+		}
+		// only the file's own variables are used, not variables with the same name in the global context
+		ctx := wf.Processed.ResolveContext(stack, false).ToStack().Top()
+		reason := ""
+		if !includeDrafts && isTrue(ctx, DraftContextName) {
+			reason = "it is a draft"
+		} else if publishDate, ok := dateValue(ctx, PublishDateContextName, file); ok &&
+			!includeFuture && publishDate.After(now) {
+			reason = "its publish date is in the future"
+		} else if expiryDate, ok := dateValue(ctx, ExpiryDateContextName, file); ok && expiryDate.Before(now) {
+			reason = "it has expired"
+		}
+		if reason != "" {
+			log.Printf("Not publishing file %s as %s.", file, reason)
+			wf.NonWritable = true
+			filesMap.WebFiles[file] = wf
+		}
+	}
+}
+
+// isTrue returns whether the variable with the given name is true, or the string "true" (as given in
+// front matter or in the command-line).
+func isTrue(context Context, name string) bool {
+	v, ok := context.Get(name)
+	if !ok {
+		return false
+	}
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		return strings.EqualFold(strings.TrimSpace(b), "true")
+	}
+	return false
+}
+
+func dateValue(context Context, name, file string) (time.Time, bool) {
+	v, ok := context.Get(name)
+	if !ok || v == nil {
+		return time.Time{}, false
+	}
+	if d, ok := v.(*expression.DateTime); ok && d.Time != nil {
+		return *d.Time, true
+	}
+	log.Printf("WARNING: ignoring variable %s in file %s as it is not a date: %v", name, file, v)
+	return time.Time{}, false
+}
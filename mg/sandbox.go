@@ -0,0 +1,94 @@
+package mg
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// pathChecker is implemented by resolvers that restrict which resolved paths may be used.
+type pathChecker interface {
+	// checkPath returns an error if the given resolved path may not be used.
+	checkPath(path string) error
+}
+
+var _ pathChecker = (*DefaultFileResolver)(nil)
+
+func (r *DefaultFileResolver) checkPath(path string) error {
+	if !r.Sandboxed {
+		return nil
+	}
+	return checkInSandbox(r.source, r.BasePath, path)
+}
+
+// checkResolvedPath checks a path resolved by the given resolver, if the resolver restricts which paths
+// may be used (see [DefaultFileResolver.Sandboxed]).
+func checkResolvedPath(resolver FileResolver, path string) error {
+	if checker, ok := resolver.(pathChecker); ok {
+		return checker.checkPath(path)
+	}
+	return nil
+}
+
+// checkInSandbox returns an error if the given path is not within the sandbox directory once both are made
+// absolute, cleaned, and have all symbolic links resolved.
+//
+// Symbolic links are only resolved in the operating system's file system, as [fs.FS] has no notion of them.
+func checkInSandbox(fsys fs.FS, sandbox, path string) error {
+	realSandbox, realPath := filepath.Clean(sandbox), filepath.Clean(path)
+	if isOSFS(fsys) {
+		var err error
+		if realSandbox, err = canonicalPath(sandbox); err != nil {
+			return err
+		}
+		if realPath, err = canonicalPath(path); err != nil {
+			return err
+		}
+	}
+	rel, err := filepath.Rel(realSandbox, realPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("path %s resolves to %s, which is outside of the sources directory %s",
+			path, realPath, realSandbox)
+	}
+	return nil
+}
+
+// canonicalPath returns the absolute path of a file with all symbolic links resolved.
+//
+// For files that do not exist, the canonical path of the closest existing parent directory is used.
+func canonicalPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	var missing []string
+	for {
+		real, err := filepath.EvalSymlinks(abs)
+		if err == nil {
+			return filepath.Join(append([]string{real}, missing...)...), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return "", err
+		}
+		missing = append([]string{filepath.Base(abs)}, missing...)
+		abs = parent
+	}
+}
+
+// checkSandbox checks that all the given files are within the sources directory.
+func (mag *Magnanimous) checkSandbox(files ...[]string) error {
+	for _, group := range files {
+		for _, file := range group {
+			if err := checkInSandbox(mag.Source, mag.SourcesDir, file); err != nil {
+				return NewError(Location{Origin: file}, IOError, err.Error())
+			}
+		}
+	}
+	return nil
+}
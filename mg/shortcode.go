@@ -0,0 +1,106 @@
+package mg
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// ShortcodesDir is the name of the directory where shortcodes are searched for, starting from the directory
+// of the file using them and going up to the root of the sources directory.
+const ShortcodesDir = "_shortcodes"
+
+// Shortcode is a compact form of the component instruction which takes no slots and needs no end
+// instruction, as in {{ < youtube id="abc" > }}.
+//
+// The component is given by the shortcode name: youtube uses the component file .../_shortcodes/_youtube.html
+// (see [ShortcodesDir]). A path may also be used instead of a name.
+//
+// If the component file does not exist, the instruction is written unchanged, so that text which only looks
+// like a shortcode, as in {{ <br> }}, is left alone, and a warning is logged in case the name is misspelled.
+type Shortcode struct {
+	component *Component
+	// warn is false for text that only looks like a shortcode because it contains HTML tags, as in {{ <b>hi</b> }}
+	warn bool
+}
+
+var shortcodeNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]*$`)
+
+var _ Content = (*Shortcode)(nil)
+var _ Inclusion = (*Shortcode)(nil)
+
+func NewShortcodeInstruction(arg string, location *Location, original string, resolver FileResolver) Content {
+	arg = strings.TrimSpace(arg)
+	if !strings.HasSuffix(arg, ">") {
+		return unevaluatedExpression(original, location)
+	}
+	arg = strings.TrimSpace(strings.TrimSuffix(arg, ">"))
+	name, argsText := arg, ""
+	if i := strings.IndexFunc(arg, unicode.IsSpace); i > 0 {
+		name, argsText = arg[:i], arg[i+1:]
+	}
+	if !shortcodeNameRegex.MatchString(name) && !strings.Contains(name, "/") {
+		return unevaluatedExpression(original, location)
+	}
+	args, err := parseComponentArgs(argsText)
+	if err != nil {
+		log.Printf("WARNING: (%s) malformed shortcode arguments: %s", location.String(), err.Error())
+		return unevaluatedExpression(original, location)
+	}
+	return &Shortcode{component: &Component{
+		Path:     shortcodePath(name),
+		Location: location,
+		Text:     original,
+		resolver: resolver,
+		args:     args,
+	}, warn: !strings.ContainsAny(name, "<>")}
+}
+
+// shortcodePath returns the path of the component file of a shortcode.
+func shortcodePath(name string) string {
+	if strings.ContainsAny(name, "/.") {
+		return name
+	}
+	return fmt.Sprintf(".../%s/_%s.html", ShortcodesDir, name)
+}
+
+func (s *Shortcode) GetPath() string {
+	return s.component.GetPath()
+}
+
+func (s *Shortcode) GetLocation() *Location {
+	return s.component.GetLocation()
+}
+
+func (s *Shortcode) IsScoped() bool {
+	return s.component.IsScoped()
+}
+
+func (s *Shortcode) Write(writer io.Writer, context Context) ([]Content, error) {
+	if !s.exists(context) {
+		if s.warn {
+			log.Printf("WARNING: (%s) shortcode component not found, writing it unchanged: %s",
+				s.component.Location.String(), s.component.Path)
+		}
+		return unevaluatedExpressions(s.component.Text, s.component.Location), nil
+	}
+	return s.component.Write(writer, context)
+}
+
+// exists returns whether the component file of the shortcode exists.
+func (s *Shortcode) exists(context Context) bool {
+	c := s.component
+	if c.resolver == nil {
+		return false
+	}
+	file := c.resolver.Resolve(c.Path, c.Location, context.ToStack().NearestLocation())
+	_, ok := c.resolver.Get(file)
+	return ok
+}
+
+func (s *Shortcode) String() string {
+	return fmt.Sprintf("Shortcode{%s}", s.component.Text)
+}
@@ -0,0 +1,94 @@
+package mg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// SitemapFileName is the name of the sitemap file written to the target directory.
+	SitemapFileName = "sitemap.xml"
+	// SiteURLContextName is the name of the global variable holding the full URL of the website,
+	// e.g. https://example.org/my-website.
+	//
+	// If it is not defined, the baseURL variable is used instead.
+	SiteURLContextName = "siteURL"
+	// BaseURLContextName is the name of the global variable commonly used to hold the base path of the website.
+	BaseURLContextName = "baseURL"
+	// SitemapContextName is the name of the variable which, if false, excludes a file from the sitemap.
+	SitemapContextName = "sitemap"
+)
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// writeSitemap writes the sitemap.xml file listing all processed files, and taxonomy pages, that are written to
+// the given output.
+func (mag *Magnanimous) writeSitemap(out Output, filesMap WebFilesMap, stack *ContextStack) error {
+	siteURL := siteURL(stack, "the sitemap")
+
+	urlSet := sitemapURLSet{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	for _, wf := range mag.publishedFiles(filesMap, stack) {
+		if v, ok := wf.context.Get(SitemapContextName); ok && v == false {
+			continue
+		}
+		urlSet.URLs = append(urlSet.URLs, sitemapURL{
+			Loc:     siteURL + filepath.ToSlash(fileLink(wf.file.Processed)),
+			LastMod: wf.file.Processed.LastUpdated.UTC().Format(time.RFC3339),
+		})
+	}
+	for _, page := range mag.taxonomyPages(filesMap, stack) {
+		urlSet.URLs = append(urlSet.URLs, sitemapURL{
+			Loc:     siteURL + "/" + filepath.ToSlash(page.targetFile),
+			LastMod: page.lastUpdated.UTC().Format(time.RFC3339),
+		})
+	}
+	sort.Slice(urlSet.URLs, func(i, j int) bool {
+		return urlSet.URLs[i].Loc < urlSet.URLs[j].Loc
+	})
+
+	return writeXMLFile(out, SitemapFileName, urlSet)
+}
+
+// siteURL returns the full URL of the website, without a trailing slash.
+//
+// If the siteURL variable is not defined, the baseURL variable is used, and a warning mentioning the given
+// output is logged if that is not a full URL.
+func siteURL(context Context, output string) string {
+	url := globalString(context, SiteURLContextName)
+	if url == "" {
+		url = globalString(context, BaseURLContextName)
+		if !strings.Contains(url, "://") {
+			log.Printf("WARNING: the %s variable is not defined, %s will not contain full URLs",
+				SiteURLContextName, output)
+		}
+	}
+	return strings.TrimSuffix(url, "/")
+}
+
+// baseURL returns the value of the baseURL variable, without a trailing slash.
+func baseURL(context Context) string {
+	return strings.TrimSuffix(globalString(context, BaseURLContextName), "/")
+}
+
+// globalString returns the value of a global variable as a string, or the empty string if it's not defined.
+func globalString(context Context, name string) string {
+	v, ok := context.Get(name)
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
@@ -18,6 +18,19 @@ var _ Content = (*SlotContent)(nil)
 var _ ContentContainer = (*SlotContent)(nil)
 var _ Definition = (*SlotContent)(nil)
 
+// SlotOrContent is the slotOr instruction, which writes the value of a variable (usually a slot given to a component),
+// or its own body if the variable is not defined, as in {{ slotOr title }}Default title{{ end }}.
+type SlotOrContent struct {
+	Name     string
+	Text     string
+	Location *Location
+	eval     *EvalContent
+	contents []Content
+}
+
+var _ Content = (*SlotOrContent)(nil)
+var _ ContentContainer = (*SlotOrContent)(nil)
+
 // slotEval is a Content that gets written when a slot is evaluated.
 type slotEval struct {
 	location *Location
@@ -36,6 +49,19 @@ func NewSlotInstruction(arg string, location *Location, original string, resolve
 	return unevaluatedExpression(original, location)
 }
 
+func NewSlotOrInstruction(arg string, location *Location, original string, resolver FileResolver) Content {
+	variable := strings.TrimSpace(arg)
+	if strings.ContainsAny(variable, " \t\n") {
+		log.Printf("WARNING: (%s) malformed slotOr instruction: %s", location.String(), arg)
+		return unevaluatedExpression(original, location)
+	}
+	eval := NewEvalInstruction(variable, location, original, resolver)
+	if e, ok := eval.(*EvalContent); ok {
+		return &SlotOrContent{Name: variable, Text: original, Location: location, eval: e}
+	}
+	return eval
+}
+
 func (s *SlotContent) GetName() string {
 	return s.Name
 }
@@ -71,3 +97,26 @@ func (s *slotEval) IsScoped() bool {
 func (s *slotEval) Write(writer io.Writer, context Context) ([]Content, error) {
 	return s.contents, nil
 }
+
+func (s *SlotOrContent) AppendContent(content Content) {
+	s.contents = append(s.contents, content)
+}
+
+func (s *SlotOrContent) GetLocation() *Location {
+	return s.Location
+}
+
+func (s *SlotOrContent) IsScoped() bool {
+	return true
+}
+
+func (s *SlotOrContent) setEscape(escape func(string) string) {
+	s.eval.setEscape(escape)
+}
+
+func (s *SlotOrContent) Write(writer io.Writer, context Context) ([]Content, error) {
+	if v, ok := context.Get(s.Name); ok && v != nil {
+		return []Content{s.eval}, nil
+	}
+	return s.contents, nil
+}
@@ -0,0 +1,251 @@
+package mg
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/renatoathaydes/magnanimous/mg/expression"
+)
+
+// DefaultTaxonomies are the taxonomies used when [Magnanimous.Taxonomies] is nil.
+var DefaultTaxonomies = map[string]string{
+	"tags":       "tag",
+	"categories": "category",
+}
+
+const (
+	// TaxonomyContextName is the name of the variable holding the name of the taxonomy in taxonomy pages.
+	TaxonomyContextName = "taxonomy"
+	// TermContextName is the name of the variable holding the term in a taxonomy term page.
+	TermContextName = "term"
+	// TermsContextName is the name of the variable holding all terms in a taxonomy overview page.
+	TermsContextName = "terms"
+	// FilesContextName is the name of the variable holding the files with a term in a taxonomy term page.
+	FilesContextName = "files"
+)
+
+// TaxonomyTerm is a term of a taxonomy, e.g. a tag, and all files that are classified with it.
+type TaxonomyTerm struct {
+	name  string
+	slug  string
+	url   string
+	files []webFileWithContext
+}
+
+var _ expression.Context = (*TaxonomyTerm)(nil)
+
+// Get implements mg.expression.Context.
+func (t *TaxonomyTerm) Get(name string) (interface{}, bool) {
+	switch name {
+	case "name":
+		return t.name, true
+	case "slug":
+		return t.slug, true
+	case "url":
+		return t.url, true
+	case "count":
+		return float64(len(t.files)), true
+	case "files":
+		return t.files, true
+	}
+	return nil, false
+}
+
+func (t *TaxonomyTerm) String() string {
+	return t.name
+}
+
+// lastUpdated returns the most recent time one of the files with the term was last updated.
+func (t *TaxonomyTerm) lastUpdated() time.Time {
+	var result time.Time
+	for _, wf := range t.files {
+		if wf.file.Processed.LastUpdated.After(result) {
+			result = wf.file.Processed.LastUpdated
+		}
+	}
+	return result
+}
+
+// taxonomyPage is a page of a taxonomy, written from a template.
+type taxonomyPage struct {
+	templatePath string
+	template     WebFile
+	// targetFile is the path of the page within the output.
+	targetFile  string
+	values      map[string]interface{}
+	lastUpdated time.Time
+}
+
+// taxonomyPages returns the pages of every taxonomy that has a template.
+//
+// Given a taxonomy named "tags" whose singular name is "tag", each term (i.e. each value found in the
+// tags variable of processed files) has a page written from the _taxonomy_tag.html (or .md) template at
+// /tags/<term>/index.html, with the term, taxonomy and files variables in context.
+// If a _taxonomy_tags.html (or .md) template exists, an overview page is also written at /tags/index.html,
+// with the taxonomy and terms variables in context.
+func (mag *Magnanimous) taxonomyPages(filesMap WebFilesMap, stack *ContextStack) []taxonomyPage {
+	taxonomies := mag.Taxonomies
+	if taxonomies == nil {
+		taxonomies = DefaultTaxonomies
+	}
+	names := make([]string, 0, len(taxonomies))
+	for name := range taxonomies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var pages []taxonomyPage
+	var files []webFileWithContext
+	for _, name := range names {
+		termTemplate, termTemplatePath, ok := mag.taxonomyTemplate(taxonomies[name], filesMap)
+		if !ok {
+			continue
+		}
+		if files == nil {
+			files = mag.publishedFiles(filesMap, stack)
+		}
+		terms := collectTerms(name, files, baseURL(stack))
+		var taxonomyLastUpdated time.Time
+		for _, term := range terms {
+			lastUpdated := term.lastUpdated()
+			if lastUpdated.After(taxonomyLastUpdated) {
+				taxonomyLastUpdated = lastUpdated
+			}
+			pages = append(pages, taxonomyPage{
+				templatePath: termTemplatePath,
+				template:     termTemplate,
+				targetFile:   filepath.Join(name, term.slug, "index.html"),
+				values: map[string]interface{}{
+					TaxonomyContextName: name,
+					TermContextName:     term.name,
+					FilesContextName:    term.files,
+				},
+				lastUpdated: lastUpdated,
+			})
+		}
+		if overview, overviewPath, ok := mag.taxonomyTemplate(name, filesMap); ok {
+			termsArray := make([]interface{}, len(terms))
+			for i, term := range terms {
+				termsArray[i] = term
+			}
+			pages = append(pages, taxonomyPage{
+				templatePath: overviewPath,
+				template:     overview,
+				targetFile:   filepath.Join(name, "index.html"),
+				values: map[string]interface{}{
+					TaxonomyContextName: name,
+					TermsContextName:    termsArray,
+				},
+				lastUpdated: taxonomyLastUpdated,
+			})
+		}
+	}
+	return pages
+}
+
+// writeTaxonomies writes the pages of every taxonomy that has a template (see [Magnanimous.taxonomyPages]).
+func (mag *Magnanimous) writeTaxonomies(out Output, filesMap WebFilesMap, stack *ContextStack) error {
+	for _, page := range mag.taxonomyPages(filesMap, stack) {
+		if err := page.write(out, stack); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mag *Magnanimous) taxonomyTemplate(name string, filesMap WebFilesMap) (WebFile, string, bool) {
+	for _, ext := range []string{".html", ".md"} {
+		path := filepath.Join(mag.SourcesDir, "processed", "_taxonomy_"+name+ext)
+		if wf, ok := filesMap.WebFiles[path]; ok {
+			return wf, path, true
+		}
+	}
+	return WebFile{}, "", false
+}
+
+// publishedFiles returns all processed files that are going to be written, with their contexts.
+func (mag *Magnanimous) publishedFiles(filesMap WebFilesMap, stack *ContextStack) []webFileWithContext {
+	processedDir := filepath.Join(mag.SourcesDir, "processed")
+	var paths []string
+	for path, wf := range filesMap.WebFiles {
+		if !wf.NonWritable && wf.BasePath == processedDir {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	files := make([]webFileWithContext, len(paths))
+	for i, path := range paths {
+		wf := filesMap.WebFiles[path]
+		files[i] = webFileWithContext{file: wf, context: wf.Processed.ResolveContext(stack, false)}
+	}
+	return files
+}
+
+// collectTerms collects the terms of a taxonomy from the given files, where baseURL is the base URL of the
+// website, without a trailing slash, used in the URL of the terms' pages.
+func collectTerms(taxonomy string, files []webFileWithContext, baseURL string) []*TaxonomyTerm {
+	termsBySlug := make(map[string]*TaxonomyTerm)
+	for _, wf := range files {
+		v, ok := wf.context.Get(taxonomy)
+		if !ok || v == nil {
+			continue
+		}
+		values, isList := v.([]interface{})
+		if !isList {
+			values = []interface{}{v}
+		}
+		for _, value := range values {
+			name := fmt.Sprint(value)
+			slug := slugify(name)
+			if slug == "" {
+				log.Printf("WARNING: ignoring %s term '%s' in file %s as it cannot be used in a URL",
+					taxonomy, name, wf.file.Name)
+				continue
+			}
+			term, ok := termsBySlug[slug]
+			if !ok {
+				term = &TaxonomyTerm{name: name, slug: slug, url: baseURL + "/" + taxonomy + "/" + slug + "/index.html"}
+				termsBySlug[slug] = term
+			}
+			term.files = append(term.files, wf)
+		}
+	}
+	terms := make([]*TaxonomyTerm, 0, len(termsBySlug))
+	for _, term := range termsBySlug {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		return terms[i].slug < terms[j].slug
+	})
+	return terms
+}
+
+func (p *taxonomyPage) write(out Output, stack *ContextStack) error {
+	pushResult := stack.Push(nil, true)
+	defer stack.Pop(pushResult)
+	for name, value := range p.values {
+		stack.Set(name, value)
+	}
+	return writeFile(out, p.templatePath, p.targetFile, p.template, stack)
+}
+
+// slugify converts a term into a string that can be safely used as a path component in URLs.
+func slugify(term string) string {
+	var b strings.Builder
+	lastWasDash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(term)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			b.WriteRune(r)
+			lastWasDash = false
+		} else if !lastWasDash && b.Len() > 0 {
+			b.WriteRune('-')
+			lastWasDash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
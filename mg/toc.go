@@ -0,0 +1,153 @@
+package mg
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+// TocInstruction is the toc instruction, which is replaced with a table of contents of the page being written.
+//
+// As the headings of a page are only known after the whole page has been written, the instruction writes a
+// placeholder, which is replaced with the table of contents by [WebFile.Write].
+type TocInstruction struct {
+	UnscopedContent
+	Text     string
+	MaxLevel int
+	Location *Location
+}
+
+var _ Content = (*TocInstruction)(nil)
+
+var (
+	tocPlaceholderRegex     = regexp.MustCompile(`<!--magnanimous-toc:([1-6])-->`)
+	tocPlaceholderOnlyRegex = regexp.MustCompile(`^\s*<!--magnanimous-toc:[1-6]-->\s*$`)
+	headingRegex            = regexp.MustCompile(`(?is)<h([1-6])(\s[^>]*)?>(.*?)</h[1-6]>`)
+	idAttributeRegex        = regexp.MustCompile(`(?i)\sid\s*=\s*"([^"]*)"`)
+)
+
+// NewTocInstruction creates a new TocInstruction.
+//
+// The argument, if given, is the maximum level of the headings to include in the table of contents.
+func NewTocInstruction(arg string, location *Location, original string) Content {
+	maxLevel := 6
+	if arg = strings.TrimSpace(arg); arg != "" {
+		level, err := strconv.Atoi(arg)
+		if err != nil || level < 1 || level > 6 {
+			log.Printf("WARNING: (%s) toc argument must be a heading level between 1 and 6: %s",
+				location.String(), arg)
+			return unevaluatedExpression(original, location)
+		}
+		maxLevel = level
+	}
+	return &TocInstruction{Text: original, MaxLevel: maxLevel, Location: location}
+}
+
+func (t *TocInstruction) GetLocation() *Location {
+	return t.Location
+}
+
+func (t *TocInstruction) Write(writer io.Writer, context Context) ([]Content, error) {
+	_, err := fmt.Fprintf(writer, "<!--magnanimous-toc:%d-->", t.MaxLevel)
+	return nil, err
+}
+
+func (t *TocInstruction) String() string {
+	return fmt.Sprintf("TocInstruction{%s}", t.Text)
+}
+
+type tocHeading struct {
+	level int
+	id    string
+	text  string
+}
+
+// insertToc replaces the toc placeholders in the given page with its table of contents.
+//
+// Headings that do not have an id are given one, generated in the same way as Markdown heading IDs.
+func insertToc(page []byte) []byte {
+	if !tocPlaceholderRegex.Match(page) {
+		return page
+	}
+	var headings []tocHeading
+	usedIDs := make(map[string]bool)
+	for _, m := range idAttributeRegex.FindAllSubmatch(page, -1) {
+		usedIDs[string(m[1])] = true
+	}
+	page = headingRegex.ReplaceAllFunc(page, func(h []byte) []byte {
+		m := headingRegex.FindSubmatch(h)
+		level := int(m[1][0] - '0')
+		attributes, body := string(m[2]), string(m[3])
+		text := strings.TrimSpace(htmlTagRegex.ReplaceAllString(body, ""))
+		var id string
+		if idMatch := idAttributeRegex.FindStringSubmatch(attributes); idMatch != nil {
+			id = idMatch[1]
+		} else {
+			id = uniqueID(blackfriday.SanitizedAnchorName(html.UnescapeString(text)), usedIDs)
+			attributes += ` id="` + id + `"`
+		}
+		headings = append(headings, tocHeading{level: level, id: id, text: text})
+		return []byte(fmt.Sprintf("<h%d%s>%s</h%d>", level, attributes, body, level))
+	})
+	return tocPlaceholderRegex.ReplaceAllFunc(page, func(p []byte) []byte {
+		maxLevel := int(tocPlaceholderRegex.FindSubmatch(p)[1][0] - '0')
+		return renderToc(headings, maxLevel)
+	})
+}
+
+func uniqueID(id string, usedIDs map[string]bool) string {
+	unique := id
+	for i := 1; usedIDs[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", id, i)
+	}
+	usedIDs[unique] = true
+	return unique
+}
+
+type tocNode struct {
+	heading  tocHeading
+	children []*tocNode
+}
+
+// renderToc renders the headings as nested lists, nesting each heading under the closest previous heading
+// with a lower level.
+func renderToc(headings []tocHeading, maxLevel int) []byte {
+	root := &tocNode{}
+	stack := []*tocNode{root}
+	for _, h := range headings {
+		if h.level > maxLevel {
+			continue
+		}
+		for len(stack) > 1 && stack[len(stack)-1].heading.level >= h.level {
+			stack = stack[:len(stack)-1]
+		}
+		node := &tocNode{heading: h}
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, node)
+		stack = append(stack, node)
+	}
+	var b bytes.Buffer
+	writeTocList(&b, root.children, ` class="toc"`)
+	return b.Bytes()
+}
+
+func writeTocList(b *bytes.Buffer, nodes []*tocNode, attributes string) {
+	b.WriteString("<ul" + attributes + ">\n")
+	for _, node := range nodes {
+		fmt.Fprintf(b, `<li><a href="#%s">%s</a>`, node.heading.id, node.heading.text)
+		if len(node.children) > 0 {
+			b.WriteString("\n")
+			writeTocList(b, node.children, "")
+			b.WriteString("\n")
+		}
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</ul>")
+}
@@ -2,16 +2,65 @@ package mg
 
 import (
 	"io"
+	"io/fs"
 )
 
 // Magnanimous is the entry point of the magnanimous library.
 //
-// It can be used to read a source directory via the ReadAll() function.
+// It can be used to read a source directory via the ReadAll() function, then write the website via the
+// WriteTo() function, or WriteOutput() for outputs other than a directory (e.g. [MemoryOutput]).
 type Magnanimous struct {
 	// SourcesDir is the directory containing Magnanimous' source code.
 	SourcesDir string
+	// Source is the file system the SourcesDir is read from, which can be, for example, an embed.FS, a zip
+	// archive or an in-memory tree. In that case, the SourcesDir is a slash-separated path within Source
+	// (or "." for its root).
+	//
+	// If nil, the SourcesDir is read from the operating system's file system.
+	//
+	// With any other Source, [Magnanimous.GitDates] has no effect, as git can only read the operating system's
+	// file system: the last updated time of files is their modification time in Source. Links can be checked in
+	// any [Output] that can read back its files (see [Magnanimous.FindBrokenLinks]), such as a [MemoryOutput].
+	Source fs.FS
 	// Location of the global context relative to the "processed" directory.
 	GlobalContex string
+	// Profile is the name of the active build profile, if any.
+	//
+	// The profile's global context file, whose name is the global context file name plus "." + Profile,
+	// is layered on top of the global context file.
+	Profile string
+	// Definitions are variables set into the global context, taking precedence over the global context files.
+	//
+	// Values that are boolean or number literals (e.g. true or 1.5) are set as such, other values are set as
+	// strings. Numbers are only converted if written in their shortest form (so 1.0 remains a string).
+	// Values in double quotes are always strings, without the quotes.
+	Definitions map[string]string
+	// IncludeDrafts makes files marked as drafts be published.
+	IncludeDrafts bool
+	// IncludeFuture makes files whose publish date is in the future be published.
+	IncludeFuture bool
+	// Taxonomies maps the name of each taxonomy (e.g. tags) to its singular name (e.g. tag).
+	//
+	// If nil, DefaultTaxonomies is used.
+	Taxonomies map[string]string
+	// Sitemap enables writing a sitemap.xml file listing all processed files.
+	Sitemap bool
+	// Feeds are the directories whose processed files are published as RSS and/or Atom feeds.
+	Feeds []Feed
+	// GitDates makes the last updated time of files be taken from the git history of the sources directory,
+	// rather than from the file system. It is only supported with sources in the operating system's file
+	// system (see Source), otherwise a warning is logged and the file modification times are used.
+	GitDates bool
+	// CodeStylesheets are the names of the code styles whose stylesheets are written to the target directory,
+	// for use with [SetCodeClasses].
+	//
+	// The first style is the default one. The stylesheets of the other styles only apply within elements with
+	// the class chroma-<style>, e.g. <body class="chroma-monokai">.
+	CodeStylesheets []string
+	// Sandbox restricts all files, and paths used by instructions, to the SourcesDir, rejecting paths and
+	// symbolic links leading outside of it (see [DefaultFileResolver.Sandboxed]).
+	// Environment variables cannot be read (the env variable is not defined) in Sandbox mode.
+	Sandbox bool
 }
 
 // WebFilesMap contains the result of reading a source directory with ReadAll().
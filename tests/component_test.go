@@ -36,3 +36,165 @@ func TestIncludeComponent(t *testing.T) {
 
 	checkParsing(t, otherProcessed, expectedCtx, "OUTER\nHi Joe! Your contents: Four is 4.\nEND")
 }
+
+func TestComponentArguments(t *testing.T) {
+	files := make(map[string]mg.WebFile)
+	resolver := mg.DefaultFileResolver{BasePath: "source", Files: &mg.WebFilesMap{WebFiles: files}}
+
+	r := bufio.NewReader(strings.NewReader("{{ param kind \"info\" }}{{ param title }}" +
+		"[{{ eval kind }}] {{ eval title }}: {{ eval __contents__ }}"))
+	processed, err := mg.ProcessReader(r, "source/processed/_note.txt", "source", 0, &resolver, time.Now())
+	check(err)
+
+	r = bufio.NewReader(strings.NewReader("{{ define name \"Joe\" }}{{ define kind \"outer\" }}" +
+		"{{ component /processed/_note.txt (title=(\"Hi \" + name), kind=\"warning\") }}one{{ end }}\n" +
+		"{{ component /processed/_note.txt (title=name) }}two{{ end }}\n" +
+		"{{ component /processed/_note.txt }}{{ slot title }}Slot{{ end }}three{{ end }}"))
+	otherProcessed, err := mg.ProcessReader(r, "source/processed/other.txt", "source", 0, &resolver, time.Now())
+	check(err)
+
+	files["source/processed/_note.txt"] = mg.WebFile{Processed: processed}
+	files["source/processed/other.txt"] = mg.WebFile{Processed: otherProcessed}
+
+	checkContents(t, otherProcessed, "[warning] Hi Joe: one\n[info] Joe: two\n[info] Slot: three")
+}
+
+func TestComponentMissingRequiredParameter(t *testing.T) {
+	files := make(map[string]mg.WebFile)
+	resolver := mg.DefaultFileResolver{BasePath: "source", Files: &mg.WebFilesMap{WebFiles: files}}
+
+	r := bufio.NewReader(strings.NewReader("{{ param title }}{{ eval title }}"))
+	processed, err := mg.ProcessReader(r, "source/processed/_note.txt", "source", 0, &resolver, time.Now())
+	check(err)
+
+	r = bufio.NewReader(strings.NewReader("{{ define title \"Page\" }}\n" +
+		"{{ component /processed/_note.txt (kind=1) }}{{ end }}"))
+	otherProcessed, err := mg.ProcessReader(r, "source/processed/other.txt", "source", 0, &resolver, time.Now())
+	check(err)
+
+	files["source/processed/_note.txt"] = mg.WebFile{Processed: processed}
+
+	stack := mg.NewContextStack(mg.NewContext())
+	wf := mg.WebFile{Processed: otherProcessed}
+	var result strings.Builder
+	shouldHaveError(t, wf.Write(&result, &stack, false, false),
+		"(source/processed/other.txt:2:1) component /processed/_note.txt requires parameter 'title'")
+}
+
+func TestComponentParameterValidation(t *testing.T) {
+	files := make(map[string]mg.WebFile)
+	resolver := mg.DefaultFileResolver{BasePath: "source", Files: &mg.WebFilesMap{WebFiles: files}}
+
+	r := bufio.NewReader(strings.NewReader("{{ param title:string }}{{ param count:number 1 }}" +
+		"{{ param subtitle?:string }}{{ param tags?:list }}" +
+		"{{ eval title }} ({{ eval count }}){{ eval subtitle }}"))
+	processed, err := mg.ProcessReader(r, "source/processed/_note.txt", "source", 0, &resolver, time.Now())
+	check(err)
+	files["source/processed/_note.txt"] = mg.WebFile{Processed: processed}
+
+	write := func(text string) (string, error) {
+		r := bufio.NewReader(strings.NewReader(text))
+		pf, err := mg.ProcessReader(r, "source/processed/other.txt", "source", 0, &resolver, time.Now())
+		check(err)
+		stack := mg.NewContextStack(mg.NewContext())
+		wf := mg.WebFile{Processed: pf}
+		var result strings.Builder
+		err = wf.Write(&result, &stack, false, false)
+		return result.String(), err
+	}
+
+	result, err := write("{{ define subtitle \"outer\" }}" +
+		"{{ component /processed/_note.txt (title=\"Hi\", tags=[\"a\"]) }}{{ end }}")
+	check(err)
+	if result != "Hi (1)" {
+		t.Errorf("Unexpected result: '%s'", result)
+	}
+
+	_, err = write("{{ define title \"Page\" }}\n{{ component /processed/_note.txt }}{{ end }}")
+	shouldHaveError(t, err,
+		"(source/processed/other.txt:2:1) component /processed/_note.txt requires parameter 'title'")
+
+	_, err = write("{{ component /processed/_note.txt (title=undefined) }}{{ end }}")
+	shouldHaveError(t, err,
+		"(source/processed/other.txt:1:1) component /processed/_note.txt requires parameter 'title'")
+
+	_, err = write("\n  {{ component /processed/_note.txt (title=\"Hi\", count=\"2\") }}{{ end }}")
+	shouldHaveError(t, err,
+		"(source/processed/other.txt:2:3) component /processed/_note.txt parameter 'count' must be a number, not a string")
+
+	_, err = write("{{ component /processed/_note.txt (title=\"Hi\", kind=1) }}{{ end }}")
+	shouldHaveError(t, err,
+		"(source/processed/other.txt:1:1) component /processed/_note.txt has no parameter 'kind'")
+}
+
+func TestShortcode(t *testing.T) {
+	files := make(map[string]mg.WebFile)
+	resolver := mg.DefaultFileResolver{BasePath: "source", Files: &mg.WebFilesMap{WebFiles: files}}
+
+	r := bufio.NewReader(strings.NewReader("{{ param kind \"info\" }}{{ param text }}" +
+		"<p class=\"{{ eval kind }}\">{{ eval text }}</p>"))
+	processed, err := mg.ProcessReader(r, "source/processed/_shortcodes/_note.html", "source", 0,
+		&resolver, time.Now())
+	check(err)
+
+	r = bufio.NewReader(strings.NewReader("# Title\n\n" +
+		"{{ < note kind=\"warning\" text=\"Be careful\" > }}\n\n" +
+		"{{<note text=`Notice`>}}\n"))
+	otherProcessed, err := mg.ProcessReader(r, "source/processed/docs/page.md", "source", 0, &resolver, time.Now())
+	check(err)
+
+	files["source/processed/_shortcodes/_note.html"] = mg.WebFile{Processed: processed}
+	files["source/processed/docs/page.md"] = mg.WebFile{Processed: otherProcessed}
+
+	checkContents(t, otherProcessed, "<h1>Title</h1>\n"+
+		"<p class=\"warning\">Be careful</p>"+
+		"<p class=\"info\">Notice</p>")
+}
+
+func TestShortcodeOnlyForExistingComponents(t *testing.T) {
+	files := make(map[string]mg.WebFile)
+	resolver := mg.DefaultFileResolver{BasePath: "source", Files: &mg.WebFilesMap{WebFiles: files}}
+
+	r := bufio.NewReader(strings.NewReader("<hr>"))
+	processed, err := mg.ProcessReader(r, "source/processed/_shortcodes/_rule.html", "source", 0,
+		&resolver, time.Now())
+	check(err)
+
+	r = bufio.NewReader(strings.NewReader("{{ < rule > }} {{ <br> }} {{ < missing x=1 > }} {{ <b>bold</b> }} " +
+		"{{ < /processed/_nothing.html > }} {{ < 1 + 2 }}"))
+	otherProcessed, err := mg.ProcessReader(r, "source/processed/page.html", "source", 0, &resolver, time.Now())
+	check(err)
+
+	files["source/processed/_shortcodes/_rule.html"] = mg.WebFile{Processed: processed}
+	files["source/processed/page.html"] = mg.WebFile{Processed: otherProcessed}
+
+	checkContents(t, otherProcessed, "<hr> {{ <br> }} {{ < missing x=1 > }} {{ <b>bold</b> }} "+
+		"{{ < /processed/_nothing.html > }} {{ < 1 + 2 }}")
+}
+
+func TestComponentSlotOr(t *testing.T) {
+	files := make(map[string]mg.WebFile)
+	resolver := mg.DefaultFileResolver{BasePath: "source", Files: &mg.WebFilesMap{WebFiles: files}}
+
+	r := bufio.NewReader(strings.NewReader("<{{ slotOr title }}Untitled {{ eval 1 + 1 }}{{ end }}>"))
+	processed, err := mg.ProcessReader(r, "source/processed/_card.txt", "source", 0, &resolver, time.Now())
+	check(err)
+
+	r = bufio.NewReader(strings.NewReader("{{ param title? }}[{{ slotOr title }}Untitled{{ end }}]"))
+	withParam, err := mg.ProcessReader(r, "source/processed/_card_param.txt", "source", 0, &resolver, time.Now())
+	check(err)
+
+	r = bufio.NewReader(strings.NewReader("{{ component /processed/_card.txt }}{{ slot title }}Slot{{ end }}{{ end }}\n" +
+		"{{ component /processed/_card.txt }}{{ end }}\n" +
+		"{{ component /processed/_card.txt (title=\"Arg\") }}{{ end }}\n" +
+		"{{ define title \"Page\" }}" +
+		"{{ component /processed/_card.txt }}{{ end }}\n" +
+		"{{ component /processed/_card_param.txt }}{{ end }}"))
+	otherProcessed, err := mg.ProcessReader(r, "source/processed/other.txt", "source", 0, &resolver, time.Now())
+	check(err)
+
+	files["source/processed/_card.txt"] = mg.WebFile{Processed: processed}
+	files["source/processed/_card_param.txt"] = mg.WebFile{Processed: withParam}
+
+	checkContents(t, otherProcessed, "<Slot>\n<Untitled 2>\n<Arg>\n<Page>\n[Untitled]")
+}
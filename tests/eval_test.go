@@ -174,3 +174,95 @@ func TestEvalWithExistingParameterFromAnotherFile(t *testing.T) {
 
 	checkParsing(t, otherProcessed, expectedCtx, "OUTER\nA = 14\nEND")
 }
+
+func TestEvalEscapesValuesInHtmlFiles(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("{{ define title \"Tom & <Jerry>\" }}" +
+		"<h1 title=\"{{ eval title }}\">{{ eval title }}</h1>\n" +
+		"<p class={{ eval \"a b\" }}>{{ eval raw(\"<b>bold</b>\") }}</p>\n" +
+		"<script>var title = \"{{ eval title }}\";</script>\n" +
+		"{{ if true }}{{ eval title }}{{ end }}"))
+	processed, err := mg.ProcessReader(r, "source/processed/page.html", "source", 0, nil, time.Now())
+	check(err)
+
+	checkParsing(t, processed, map[string]interface{}{"title": "Tom & <Jerry>"},
+		"<h1 title=\"Tom &amp; &lt;Jerry&gt;\">Tom &amp; &lt;Jerry&gt;</h1>\n"+
+			"<p class=a&#32;b><b>bold</b></p>\n"+
+			"<script>var title = \"Tom \\u0026 \\u003CJerry\\u003E\";</script>\n"+
+			"Tom &amp; &lt;Jerry&gt;")
+}
+
+func TestEvalEscapesValuesInStyles(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("{{ define color \"red;}</style><script>\" }}" +
+		"<style>p { color: {{ eval color }}; border: {{ eval \"1px solid #ccc\" }}; }</style>" +
+		"<p>{{ eval color }}</p>"))
+	processed, err := mg.ProcessReader(r, "source/processed/page.html", "source", 0, nil, time.Now())
+	check(err)
+
+	checkParsing(t, processed, map[string]interface{}{"color": "red;}</style><script>"},
+		"<style>p { color: red\\3b \\7d \\3c \\2f style\\3e \\3c script\\3e ; border: 1px solid #ccc; }</style>"+
+			"<p>red;}&lt;/style&gt;&lt;script&gt;</p>")
+}
+
+func TestEvalFiltersUnsafeURLs(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("{{ define bad \"javascript:alert('x')\" }}" +
+		"<a href=\"{{ eval bad }}\">1</a> <a href={{ eval \" JavaScript:x\" }}>2</a> " +
+		"<img src='{{ eval \"data:text/html,x\" }}'> <a href=\"java{{ eval \"script:x\" }}\">3</a>\n" +
+		"<a href=\"{{ eval \"https://a.org/?q=a&b\" }}\">4</a> <a href=\"/posts/{{ eval bad }}\">5</a> " +
+		"<a href=\"{{ eval \"docs/a.html#x:y\" }}\">6</a> <a title=\"{{ eval bad }}\">7</a> " +
+		"<a href=\"{{ eval raw(bad) }}\">8</a>"))
+	processed, err := mg.ProcessReader(r, "source/processed/page.html", "source", 0, nil, time.Now())
+	check(err)
+
+	checkParsing(t, processed, map[string]interface{}{"bad": "javascript:alert('x')"},
+		"<a href=\"#unsafe-url\">1</a> <a href=#unsafe-url>2</a> "+
+			"<img src='#unsafe-url'> <a href=\"java#unsafe-url\">3</a>\n"+
+			"<a href=\"https://a.org/?q=a&amp;b\">4</a> <a href=\"/posts/javascript:alert(&#39;x&#39;)\">5</a> "+
+			"<a href=\"docs/a.html#x:y\">6</a> <a title=\"javascript:alert(&#39;x&#39;)\">7</a> "+
+			"<a href=\"javascript:alert('x')\">8</a>")
+}
+
+func TestEvalKeepsRawStringsThroughOperators(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("{{ define b raw(\"<b>\") }}" +
+		"<p>{{ eval b + \"bold\" + raw(\"</b>\") }} {{ eval \"<i>\" + 1 }} {{ eval b + 1 }} " +
+		"{{ eval b == \"<b>\" }} {{ eval raw(\"\") || \"<none>\" }}</p>"))
+	processed, err := mg.ProcessReader(r, "source/processed/page.html", "source", 0, nil, time.Now())
+	check(err)
+
+	checkContents(t, processed, "<p><b>bold</b> &lt;i&gt;1 <b>1 true &lt;none&gt;</p>")
+}
+
+func TestEvalEscapesValuesAddedToRawStrings(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("---\ntitle: \"<script>alert(1)</script>\"\n---\n" +
+		"<h1>{{ eval raw(\"<b>\") + title + raw(\"</b>\") }}</h1>" +
+		"<h2>{{ eval title + raw(\"<br>\") }}</h2>"))
+	processed, err := mg.ProcessReader(r, "source/processed/page.html", "source", 0, nil, time.Now())
+	check(err)
+
+	checkContents(t, processed, "<h1><b>&lt;script&gt;alert(1)&lt;/script&gt;</b></h1>"+
+		"<h2>&lt;script&gt;alert(1)&lt;/script&gt;<br></h2>")
+}
+
+func TestEvalDoesNotEscapeValuesInOtherFiles(t *testing.T) {
+	for _, file := range []string{"source/processed/page.md", "source/processed/page.txt"} {
+		r := bufio.NewReader(strings.NewReader("{{ eval \"<b>Tom & Jerry</b>\" }}"))
+		processed, err := mg.ProcessReader(r, file, "source", 0, nil, time.Now())
+		check(err)
+
+		var expected string
+		if strings.HasSuffix(file, ".md") {
+			expected = "<p><b>Tom &amp; Jerry</b></p>\n"
+		} else {
+			expected = "<b>Tom & Jerry</b>"
+		}
+		checkParsing(t, processed, emptyContext, expected)
+	}
+}
+
+func TestEvalEscapeFunctions(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("{{ eval escapeHtml(\"<a>\") }} {{ eval escapeAttr(\"a=b c\") }} " +
+		"{{ eval escapeJs(\"it's\") }} {{ eval urlEncode(\"a b&c\") }}"))
+	processed, err := mg.ProcessReader(r, "source/processed/page.txt", "source", 0, nil, time.Now())
+	check(err)
+
+	checkParsing(t, processed, emptyContext, "&lt;a&gt; a&#61;b&#32;c it\\'s a+b%26c")
+}
@@ -0,0 +1,121 @@
+package expression
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/renatoathaydes/magnanimous/mg/expression"
+)
+
+func TestRangeExpr(t *testing.T) {
+	v, err := expression.Eval(`range(1, 5)`, nil)
+
+	if err != nil {
+		t.Fatalf("Could not evaluate: %v", err)
+	}
+
+	if !reflect.DeepEqual(v, []interface{}{1.0, 2.0, 3.0, 4.0, 5.0}) {
+		t.Errorf("Expected '[1 2 3 4 5]' but got '%v'", v)
+	}
+}
+
+func TestRangeTooLargeExpr(t *testing.T) {
+	_, err := expression.Eval(`range(1, 1000000000)`, nil)
+
+	if err == nil {
+		t.Fatal("Expected error due to range being too large")
+	}
+	expected := "range from 1 to 1e+09 with step 1 has too many elements (maximum is 100000)"
+	if err.Error() != expected {
+		t.Errorf("Expected error '%s' but got '%s'", expected, err.Error())
+	}
+}
+
+func TestRangeWithStepExpr(t *testing.T) {
+	v, err := expression.Eval(`range(0, 10, 5)`, nil)
+
+	if err != nil {
+		t.Fatalf("Could not evaluate: %v", err)
+	}
+
+	if !reflect.DeepEqual(v, []interface{}{0.0, 5.0, 10.0}) {
+		t.Errorf("Expected '[0 5 10]' but got '%v'", v)
+	}
+}
+
+func TestRangeDescendingExpr(t *testing.T) {
+	ctx := map[string]interface{}{"n": 3.0}
+	v, err := expression.Eval(`range(n, 1)`, &expression.MapContext{Map: ctx})
+
+	if err != nil {
+		t.Fatalf("Could not evaluate: %v", err)
+	}
+
+	if !reflect.DeepEqual(v, []interface{}{3.0, 2.0, 1.0}) {
+		t.Errorf("Expected '[3 2 1]' but got '%v'", v)
+	}
+}
+
+func TestRangeInvalidArgs(t *testing.T) {
+	_, err := expression.Eval(`range("a", 2)`, nil)
+
+	if err == nil {
+		t.Fatal("Expected error but range evaluated successfully")
+	}
+}
+
+func TestUnknownFunction(t *testing.T) {
+	_, err := expression.Eval(`foo(1)`, nil)
+
+	if err == nil || err.Error() != "unknown function: foo" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestFunctionFromContext(t *testing.T) {
+	double := expression.Function(func(args []interface{}) (interface{}, error) {
+		return args[0].(float64) * 2, nil
+	})
+	ctx := map[string]interface{}{"double": double}
+	v, err := expression.Eval(`double(21)`, &expression.MapContext{Map: ctx})
+
+	if err != nil {
+		t.Fatalf("Could not evaluate: %v", err)
+	}
+
+	if v != 42.0 {
+		t.Errorf("Expected '42' but got '%v'", v)
+	}
+}
+
+func TestRawExpr(t *testing.T) {
+	v, err := expression.Eval(`raw("<b>")`, nil)
+
+	if err != nil {
+		t.Fatalf("Could not evaluate: %v", err)
+	}
+
+	if v != expression.RawString("<b>") {
+		t.Errorf("Expected raw string '<b>' but got '%v' (%T)", v, v)
+	}
+}
+
+func TestEscapeFunctionsExpr(t *testing.T) {
+	examples := map[string]expression.RawString{
+		`escapeHtml("<a href='x'>&</a>")`: "&lt;a href=&#39;x&#39;&gt;&amp;&lt;/a&gt;",
+		`escapeAttr("a b=c")`:             "a&#32;b&#61;c",
+		`escapeJs("it's <b>")`:            `it\'s \u003Cb\u003E`,
+		`escapeCss("red;} a.b#c")`:        `red\3b \7d  a.b#c`,
+		`urlEncode("a b/c?d")`:            "a+b%2Fc%3Fd",
+		`escapeHtml(2)`:                   "2",
+	}
+	for expr, expected := range examples {
+		v, err := expression.Eval(expr, nil)
+		if err != nil {
+			t.Fatalf("Could not evaluate %s: %v", expr, err)
+		}
+		if v != expected {
+			t.Errorf("Expected %s to be '%s' but got '%v'", expr, expected, v)
+		}
+	}
+}
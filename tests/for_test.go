@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/renatoathaydes/magnanimous/mg"
@@ -232,6 +233,24 @@ func TestForFiles(t *testing.T) {
 			"Title Second File\n")
 }
 
+func TestForFilesInDirectoryLookingLikeFunctionCall(t *testing.T) {
+	files, dir := CreateTempFiles(map[string]string{
+		"processed/posts(2020)/f1.txt": "{{ define title \"File 1\" }}",
+	})
+	defer os.RemoveAll(dir)
+
+	resolver := mg.DefaultFileResolver{BasePath: dir, Files: &files}
+
+	r := bufio.NewReader(strings.NewReader("{{ for path posts(2020) }}Title {{ eval path.title }}{{ end }}"))
+	processed, err := mg.ProcessReader(r, filepath.Join(dir, "processed/hi.txt"), dir, 11, &resolver, time.Now())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkContents(t, processed, "Title File 1")
+}
+
 func TestForFilesScope(t *testing.T) {
 
 	// create a bunch of files for testing
@@ -626,3 +645,218 @@ func TestForFilesGroupBy(t *testing.T) {
 			"E\n"+
 			"-- end third --\n")
 }
+
+func TestForFilesGroupByLastUpdated(t *testing.T) {
+	day1 := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	day2 := time.Date(2020, 2, 3, 10, 0, 0, 0, time.UTC)
+	source := fstest.MapFS{
+		"processed/posts/a.txt": {Data: []byte("{{ define title \"A\" }}"), ModTime: day1},
+		"processed/posts/b.txt": {Data: []byte("{{ define title \"B\" }}"), ModTime: day2},
+		"processed/posts/c.txt": {Data: []byte("{{ define title \"C\" }}"), ModTime: day1},
+		"processed/index.txt": {Data: []byte("{{ for g (groupBy lastUpdated) /processed/posts }}" +
+			"{{ eval g.group }}:{{ for post eval g.values }} {{ eval post.title }}{{ end }}\n{{ end }}")},
+	}
+
+	mag := mg.Magnanimous{SourcesDir: ".", Source: source}
+	webFiles, err := mag.ReadAll()
+	check(err)
+
+	out := mg.MemoryOutput{}
+	check(mag.WriteOutput(out, webFiles))
+
+	verifyEqual(1, t, string(out["index.txt"]), "01 Jan 2020, 10:00 AM: A C\n03 Feb 2020, 10:00 AM: B\n")
+}
+
+func TestForFilesComputedProperties(t *testing.T) {
+
+	// create a bunch of files for testing
+	files, dir := CreateTempFiles(map[string]string{
+		"processed/posts/p1.md":  "# Post 1\n\nFirst paragraph here.\n\nSecond paragraph.",
+		"processed/posts/p2.md":  "Intro text\n<!--more-->\nThe rest of the post.",
+		"processed/posts/p3.txt": "{{ define summary \"Custom summary\" }}Plain text",
+	})
+	defer os.RemoveAll(dir)
+
+	resolver := mg.DefaultFileResolver{BasePath: dir, Files: &files}
+
+	r := bufio.NewReader(strings.NewReader("{{ for post /processed/posts }}\n" +
+		"{{ eval post.summary }} ({{ eval post.wordCount }} words, {{ eval post.readingTime }} min)\n" +
+		"{{ end }}"))
+	processed, err := mg.ProcessReader(r, filepath.Join(dir, "processed/hi.txt"), dir, 11, &resolver, time.Now())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkContents(t, processed,
+		"\n<p>First paragraph here.</p> (7 words, 1 min)\n\n"+
+			"<p>Intro text</p> (7 words, 1 min)\n\n"+
+			"Custom summary (2 words, 1 min)\n")
+}
+
+func TestForFilesContentCycle(t *testing.T) {
+	files, dir := CreateTempFiles(map[string]string{
+		"processed/posts/p1.txt": "Post 1",
+	})
+	defer os.RemoveAll(dir)
+
+	resolver := mg.DefaultFileResolver{BasePath: dir, Files: &files}
+
+	r := bufio.NewReader(strings.NewReader("{{ for post /processed/posts }}" +
+		"[{{ eval post.content }}]{{ end }}"))
+	index := filepath.Join(dir, "processed/posts/index.txt")
+	processed, err := mg.ProcessReader(r, index, dir, 11, &resolver, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	files.WebFiles[index] = mg.WebFile{Processed: processed, BasePath: filepath.Dir(index), Name: "index.txt"}
+
+	// the index file cannot include its own content
+	wf := files.WebFiles[index]
+	stack := mg.NewContextStack(mg.NewContext())
+	var b strings.Builder
+	err = wf.Write(&b, &stack, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyEqual(0, t, b.String(), "[][Post 1]")
+}
+
+func TestForFilesMetadata(t *testing.T) {
+
+	// create a bunch of files for testing
+	files, dir := CreateTempFiles(map[string]string{
+		"processed/posts/a.md":      "# A",
+		"processed/posts/b.html":    "<p>B</p>",
+		"processed/posts/c/c.txt":   "C",
+		"processed/posts/older.txt": "Old",
+	})
+	defer os.RemoveAll(dir)
+
+	for i, name := range []string{"a.md", "older.txt", "b.html"} {
+		lastUpdated := time.Date(2020, 1, 10-i, 12, 0, 0, 0, time.UTC)
+		files.WebFiles[filepath.Join(dir, "processed/posts", name)].Processed.LastUpdated = lastUpdated
+	}
+
+	resolver := mg.DefaultFileResolver{BasePath: dir, Files: &files}
+
+	r := bufio.NewReader(strings.NewReader("{{ for post (sortBy lastUpdated) /processed/posts }}" +
+		"{{ eval post.name }} {{ eval post.ext }} {{ eval post.size }} {{ eval post.url }} " +
+		"{{ eval post.path }} {{ eval post.dir }} {{ eval post.lastUpdated }}\n{{ end }}"))
+	processed, err := mg.ProcessReader(r, filepath.Join(dir, "processed/hi.txt"), dir, 11, &resolver, time.Now())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkContents(t, processed,
+		"b.html .html 8 /processed/posts/b.html /processed/posts/b.html /processed/posts 08 Jan 2020, 12:00 PM\n"+
+			"older.txt .txt 3 /processed/posts/older.txt /processed/posts/older.txt /processed/posts 09 Jan 2020, 12:00 PM\n"+
+			"a.md .md 3 /processed/posts/a.html /processed/posts/a.md /processed/posts 10 Jan 2020, 12:00 PM\n")
+}
+
+func TestForFilesMetadataDoesNotShadowVariables(t *testing.T) {
+	source := fstest.MapFS{
+		"processed/_global_context": {Data: []byte("{{ define name \"My Site\" }}{{ define url \"http://x\" }}")},
+		"processed/posts/a.md":      {Data: []byte("{{ define url \"/custom\" }}{{ define site name }}# A")},
+		"processed/posts/b.md":      {Data: []byte("{{ define site name + \" \" + url }}# B")},
+		"processed/index.txt": {Data: []byte("{{ for post (sortBy url) /processed/posts }}" +
+			"{{ eval post.name }} {{ eval post.url }} {{ eval post.site }}|{{ end }}{{ eval name }} {{ eval url }}")},
+	}
+
+	mag := mg.Magnanimous{SourcesDir: ".", Source: source}
+	webFiles, err := mag.ReadAll()
+	check(err)
+
+	out := mg.MemoryOutput{}
+	check(mag.WriteOutput(out, webFiles))
+
+	verifyEqual(1, t, string(out["index.txt"]),
+		"a.md /custom My Site|b.md /posts/b.html My Site http://x|My Site http://x")
+}
+
+func TestForFilesRenderedPropertiesAreNotEscaped(t *testing.T) {
+	source := fstest.MapFS{
+		"processed/posts/a.md": {Data: []byte("First *post* & more.\n\nSecond.")},
+		"processed/index.html": {Data: []byte("{{ for post /processed/posts }}" +
+			"<div>{{ eval post.summary }}</div>{{ eval post.content }}{{ end }}")},
+	}
+
+	mag := mg.Magnanimous{SourcesDir: ".", Source: source}
+	webFiles, err := mag.ReadAll()
+	check(err)
+
+	out := mg.MemoryOutput{}
+	check(mag.WriteOutput(out, webFiles))
+
+	verifyEqual(1, t, string(out["index.html"]), "<div><p>First <em>post</em> &amp; more.</p></div>"+
+		"<p>First <em>post</em> &amp; more.</p>\n\n<p>Second.</p>\n")
+}
+
+func TestForRange(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("Stars:" +
+		"{{ for i range(1, 5) }} {{ eval i }}{{ end }}\n" +
+		"Pages:" +
+		"{{ define pages 3 }}" +
+		"{{ for p (reverse) range(1, pages) }} {{ eval p }}{{ end }}"))
+	processed, err := mg.ProcessReader(r, "source/processed/hi.txt", "source", 11, nil, time.Now())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkContents(t, processed,
+		"Stars: 1 2 3 4 5\n"+
+			"Pages: 3 2 1")
+}
+
+func TestForMap(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("Menu:\n" +
+		"{{ for item eval menu }}" +
+		"{{ eval item.key }} -> {{ eval item.value.url }}\n" +
+		"{{ end }}"))
+	processed, err := mg.ProcessReader(r, "source/processed/hi.txt", "source", 11, nil, time.Now())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := mg.NewContext()
+	ctx.Set("menu", map[string]interface{}{
+		"home":  map[string]interface{}{"url": "/index.html"},
+		"about": map[string]interface{}{"url": "/about.html"},
+		"docs":  map[string]interface{}{"url": "/docs.html"},
+	})
+	stack := mg.NewContextStack(ctx)
+	wf := mg.WebFile{Processed: processed}
+	var result strings.Builder
+	err = wf.Write(&result, &stack, false, false)
+	check(err)
+
+	verifyEqual(0, t, result.String(), "Menu:\n"+
+		"about -> /about.html\n"+
+		"docs -> /docs.html\n"+
+		"home -> /index.html\n")
+}
+
+func TestForMapSortByValueLimit(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader(
+		"{{ for item (sortBy value reverse limit 2) eval scores }}" +
+			"{{ eval item.key }}={{ eval item.value }} " +
+			"{{ end }}"))
+	processed, err := mg.ProcessReader(r, "source/processed/hi.txt", "source", 11, nil, time.Now())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := mg.NewContext()
+	ctx.Set("scores", map[string]interface{}{"a": 3.0, "b": 10.0, "c": 1.0, "d": 5.0})
+	stack := mg.NewContextStack(ctx)
+	wf := mg.WebFile{Processed: processed}
+	var result strings.Builder
+	err = wf.Write(&result, &stack, false, false)
+	check(err)
+
+	verifyEqual(0, t, result.String(), "b=10 d=5 ")
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renatoathaydes/magnanimous/mg"
+	"github.com/renatoathaydes/magnanimous/mg/expression"
+)
+
+func TestYamlFrontMatter(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("---\n" +
+		"title: My Post\n" +
+		"order: 2\n" +
+		"tags: [go, web]\n" +
+		"author:\n" +
+		"  name: Joe\n" +
+		"---\n" +
+		"{{ eval title }} by {{ eval author.name }} ({{ eval order + 1 }})"))
+	processed, err := mg.ProcessReader(r, "source/processed/hi.txt", "source", 11, nil, time.Now())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedCtx := make(map[string]interface{})
+	expectedCtx["title"] = "My Post"
+	expectedCtx["order"] = float64(2)
+	expectedCtx["tags"] = []interface{}{"go", "web"}
+	expectedCtx["author"] = map[string]interface{}{"name": "Joe"}
+
+	checkParsing(t, processed, expectedCtx, "My Post by Joe (3)")
+}
+
+func TestTomlFrontMatter(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("+++\r\n" +
+		"title = \"My Post\"\r\n" +
+		"draft = false\r\n" +
+		"+++\r\n" +
+		"# {{ eval title }}"))
+	processed, err := mg.ProcessReader(r, "source/processed/hi.md", "source", 11, nil, time.Now())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedCtx := make(map[string]interface{})
+	expectedCtx["title"] = "My Post"
+	expectedCtx["draft"] = false
+
+	checkParsing(t, processed, expectedCtx, "<h1>My Post</h1>\n")
+}
+
+func TestFrontMatterDate(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("---\n" +
+		"date: 2019-03-20\n" +
+		"---\n" +
+		"{{ eval date }}"))
+	processed, err := mg.ProcessReader(r, "source/processed/hi.txt", "source", 11, nil, time.Now())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	date, err := time.Parse("2006-01-02", "2019-03-20")
+	check(err)
+	expectedCtx := make(map[string]interface{})
+	expectedCtx["date"] = &expression.DateTime{Time: &date, Format: expression.DefaultDateTimeFormat}
+
+	checkParsing(t, processed, expectedCtx, "20 Mar 2019, 12:00 AM")
+}
+
+func TestFrontMatterNotAtTopIsIgnored(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("Hello\n---\ntitle: no\n---\n"))
+	processed, err := mg.ProcessReader(r, "source/processed/hi.txt", "source", 11, nil, time.Now())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkParsing(t, processed, emptyContext, "Hello\n---\ntitle: no\n---\n")
+}
+
+func TestFrontMatterNotClosed(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("---\ntitle: no\n"))
+	_, err := mg.ProcessReader(r, "source/processed/hi.txt", "source", 11, nil, time.Now())
+
+	shouldHaveError(t, err, "(source/processed/hi.txt:1:1) front matter was not closed with '---'")
+}
+
+func TestFrontMatterKeepsRowNumbers(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("---\ntitle: hi\n---\nhello {{ include abc"))
+	_, err := mg.ProcessReader(r, "source/processed/hi.txt", "source", 11, nil, time.Now())
+
+	shouldHaveError(t, err,
+		"(source/processed/hi.txt:4:21) instruction started at (4:7) was not properly closed with '}}'")
+}
+
+func TestForFilesGroupByFrontMatterTags(t *testing.T) {
+	files, dir := CreateTempFiles(map[string]string{
+		"processed/posts/a.md": "---\ntitle: A\ntags: [go, web]\n---\n",
+		"processed/posts/b.md": "---\ntitle: B\ntags: [web]\n---\n",
+		"processed/posts/c.md": "---\ntitle: C\ntags: [go]\n---\n",
+	})
+	defer os.RemoveAll(dir)
+
+	resolver := mg.DefaultFileResolver{BasePath: dir, Files: &files}
+
+	r := bufio.NewReader(strings.NewReader(
+		"{{ for tag (groupBy tags) /processed/posts }}" +
+			"{{ eval tag.group }}:" +
+			"{{ for post eval tag.values }} {{ eval post.title }}{{ end }}\n" +
+			"{{ end }}"))
+	processed, err := mg.ProcessReader(r, filepath.Join(dir, "processed/hi.txt"), dir, 11, &resolver, time.Now())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkContents(t, processed, "go: A C\nweb: A B\n")
+}
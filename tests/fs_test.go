@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/renatoathaydes/magnanimous/mg"
+)
+
+func TestReadFromFSWriteToMemory(t *testing.T) {
+	modTime := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	source := fstest.MapFS{
+		"site/processed/index.html": {Data: []byte("{{ include _header.html }}" +
+			"<p>{{ eval data.team.name }}, {{ eval date[path[\"/static/logo.svg\"]] }}</p>")},
+		"site/processed/_header.html":    {Data: []byte("<h1>{{ eval title }}</h1>")},
+		"site/processed/_global_context": {Data: []byte("{{ define title \"Home\" }}")},
+		"site/processed/docs/guide.md":   {Data: []byte("# Guide\n\n[home](../index.html)\n")},
+		"site/static/logo.svg":           {Data: []byte("<svg/>"), ModTime: modTime},
+		"site/data/team.yaml":            {Data: []byte("name: Team A\n")},
+	}
+
+	mag := mg.Magnanimous{SourcesDir: "site", Source: source}
+	webFiles, err := mag.ReadAll()
+	check(err)
+
+	out := mg.MemoryOutput{}
+	check(mag.WriteOutput(out, webFiles))
+
+	var names []string
+	for name := range out {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{"docs/guide.html", "index.html", "logo.svg"}) {
+		t.Fatalf("Unexpected output files: %v", names)
+	}
+	verifyEqual(1, t, string(out["index.html"]), "<h1>Home</h1><p>Team A, 04 Mar 2021, 05:06 AM</p>")
+	verifyEqual(2, t, string(out["docs/guide.html"]),
+		"<h1>Guide</h1>\n\n<p><a href=\"../index.html\">home</a></p>\n")
+	verifyEqual(3, t, string(out["logo.svg"]), "<svg/>")
+}
+
+func TestReadFromFSRoot(t *testing.T) {
+	source := fstest.MapFS{
+		"processed/index.txt":  {Data: []byte("{{ includeRaw /notes.txt }}")},
+		"notes.txt":            {Data: []byte("{{ raw }}")},
+		"processed/other.txt":  {Data: []byte("other")},
+		"static/css/style.css": {Data: []byte("body {}")},
+	}
+
+	mag := mg.Magnanimous{SourcesDir: ".", Source: source}
+	webFiles, err := mag.ReadAll()
+	check(err)
+
+	out := mg.MemoryOutput{}
+	check(mag.WriteOutput(out, webFiles))
+
+	expected := mg.MemoryOutput{
+		"index.txt":     []byte("{{ raw }}"),
+		"other.txt":     []byte("other"),
+		"css/style.css": []byte("body {}"),
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("Expected %v but was %v", expected, out)
+	}
+}
+
+func TestSandboxedFSRejectsPathsOutsideSources(t *testing.T) {
+	source := fstest.MapFS{
+		"site/processed/index.txt": {Data: []byte("{{ includeRaw ../../secret.txt }}")},
+		"secret.txt":               {Data: []byte("secret")},
+	}
+
+	mag := mg.Magnanimous{SourcesDir: "site", Source: source, Sandbox: true}
+	webFiles, err := mag.ReadAll()
+	check(err)
+
+	err = mag.WriteOutput(mg.MemoryOutput{}, webFiles)
+
+	shouldHaveError(t, err, "(site/processed/index.txt:1:1) cannot use path ../../secret.txt: "+
+		"path secret.txt resolves to secret.txt, which is outside of the sources directory site")
+}
+
+func TestGitDatesFallBackToModificationTimesWithFS(t *testing.T) {
+	modTime := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	source := fstest.MapFS{
+		"processed/index.txt": {Data: []byte("{{ eval date[path[\"/processed/index.txt\"]] }}"), ModTime: modTime},
+	}
+
+	mag := mg.Magnanimous{SourcesDir: ".", Source: source, GitDates: true}
+	webFiles, err := mag.ReadAll()
+	check(err)
+
+	out := mg.MemoryOutput{}
+	check(mag.WriteOutput(out, webFiles))
+
+	verifyEqual(1, t, string(out["index.txt"]), "04 Mar 2021, 05:06 AM")
+	if by := webFiles.WebFiles["processed/index.txt"].Processed.LastUpdatedBy; by != "" {
+		t.Errorf("Expected no author, got %s", by)
+	}
+}
@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/renatoathaydes/magnanimous/mg"
+)
+
+func git(t *testing.T, dir string, env []string, args ...string) {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.org"},
+		args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
+
+func TestGitDates(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	dir, err := os.MkdirTemp("", "git_dates")
+	check(err)
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) {
+		check(os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0770))
+		check(os.WriteFile(filepath.Join(dir, name), []byte(content), 0660))
+	}
+	write("processed/committed.md", "Committed")
+	write("processed/changed.md", "Changed")
+	write("static/style.css", "body {}")
+
+	git(t, dir, nil, "init", "-q")
+	git(t, dir, nil, "add", ".")
+	git(t, dir, []string{"GIT_AUTHOR_DATE=2020-02-03T04:05:06Z", "GIT_COMMITTER_DATE=2020-02-03T04:05:06Z"},
+		"commit", "-q", "-m", "first commit", "--author", "Mary <mary@example.org>")
+
+	write("processed/changed.md", "Changed again")
+	write("processed/untracked.md", "Untracked")
+
+	mag := mg.Magnanimous{SourcesDir: dir, GitDates: true}
+	webFiles, err := mag.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commitTime := time.Date(2020, 2, 3, 4, 5, 6, 0, time.UTC)
+	for _, file := range []string{"processed/committed.md", "static/style.css"} {
+		wf := webFiles.WebFiles[filepath.Join(dir, file)]
+		if !wf.Processed.LastUpdated.Equal(commitTime) {
+			t.Errorf("Expected %s to be last updated at %v, but was %v", file, commitTime, wf.Processed.LastUpdated)
+		}
+		if wf.Processed.LastUpdatedBy != "Mary" {
+			t.Errorf("Expected %s to be last updated by Mary, but was '%s'", file, wf.Processed.LastUpdatedBy)
+		}
+	}
+	for _, file := range []string{"processed/changed.md", "processed/untracked.md"} {
+		wf := webFiles.WebFiles[filepath.Join(dir, file)]
+		if !wf.Processed.LastUpdated.After(commitTime) {
+			t.Errorf("Expected %s to keep its modification time, but was %v", file, wf.Processed.LastUpdated)
+		}
+		if wf.Processed.LastUpdatedBy != "" {
+			t.Errorf("Expected %s to have no author, but was '%s'", file, wf.Processed.LastUpdatedBy)
+		}
+	}
+}
@@ -80,6 +80,7 @@ func CreateTempFiles(files map[string]string) (mg.WebFilesMap, string) {
 		fileReader := bufio.NewReader(strings.NewReader(content))
 		pf, err := mg.ProcessReader(fileReader, name, "", len(content), nil, time.Now())
 		check(err)
+		pf.Size = int64(len(content))
 		filesMap.WebFiles[filepath.Join(dir, name)] = mg.WebFile{
 			Processed:   pf,
 			Name:        filepath.Base(name),
@@ -163,7 +164,11 @@ func checkContents(t *testing.T,
 }
 
 func runMg(t *testing.T, project string) string {
-	mag := mg.Magnanimous{SourcesDir: project}
+	return runMagnanimous(t, mg.Magnanimous{SourcesDir: project})
+}
+
+func runMagnanimous(t *testing.T, mag mg.Magnanimous) string {
+	project := mag.SourcesDir
 	webFiles, err := mag.ReadAll()
 	if err != nil {
 		t.Fatal(err)
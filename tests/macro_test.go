@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renatoathaydes/magnanimous/mg"
+)
+
+func TestMacro(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader(
+		"{{ macro badge(text, n) }}<b>{{ eval text }}:{{ eval n + 1 }}</b>{{ end }}" +
+			"{{ define text \"outer\" }}" +
+			"{{ eval badge(\"new\", 2) }} {{ eval badge(text + \"!\", 0) }} {{ eval text }}"))
+	processed, err := mg.ProcessReader(r, "source/processed/hi.html", "source", 0, nil, time.Now())
+	check(err)
+
+	checkContents(t, processed, "<b>new:3</b> <b>outer!:1</b> outer")
+}
+
+func TestMacroWithoutParameters(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader(
+		"{{ macro hr() }}<hr>{{ end }}{{ for i range(1, 2) }}{{ eval hr() }}{{ end }}"))
+	processed, err := mg.ProcessReader(r, "source/processed/hi.html", "source", 0, nil, time.Now())
+	check(err)
+
+	checkContents(t, processed, "<hr><hr>")
+}
+
+func TestMacroWrongNumberOfArguments(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader(
+		"{{ macro badge(text) }}<b>{{ eval text }}</b>{{ end }}{{ eval badge() }}"))
+	processed, err := mg.ProcessReader(r, "source/processed/hi.html", "source", 0, nil, time.Now())
+	check(err)
+
+	checkContents(t, processed, "{{ eval badge() }}")
+}
+
+func TestMacroRecursion(t *testing.T) {
+	write := func(text string) error {
+		r := bufio.NewReader(strings.NewReader(text))
+		processed, err := mg.ProcessReader(r, "source/processed/hi.html", "source", 0, nil, time.Now())
+		check(err)
+		stack := mg.NewContextStack(mg.NewContext())
+		wf := mg.WebFile{Processed: processed}
+		var result strings.Builder
+		return wf.Write(&result, &stack, false, false)
+	}
+
+	shouldHaveError(t, write("{{ macro loop(n) }}{{ eval loop(n + 1) }}{{ end }}{{ eval loop(1) }}"),
+		"(source/processed/hi.html:1:1) Macro loop exceeded the maximum call depth (100)")
+
+	shouldHaveError(t, write("{{ macro a() }}<{{ eval b() }}>{{ end }}\n"+
+		"{{ macro b() }}{{ eval a() }}{{ end }}\n{{ eval a() }}"),
+		"(source/processed/hi.html:1:1) Macro a exceeded the maximum call depth (100)")
+
+	check(write("{{ macro a() }}A{{ end }}{{ macro b() }}{{ eval a() }}{{ eval a() }}{{ end }}" +
+		"{{ eval b() }}{{ eval a() }}"))
+}
+
+func TestRecursiveMacroWithBaseCase(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader(
+		"{{ macro c(n) }}{{ if n > 0 }}{{ eval n }}{{ eval c(n - 1) }}{{ end }}{{ end }}{{ eval c(3) }}"))
+	processed, err := mg.ProcessReader(r, "source/processed/hi.html", "source", 0, nil, time.Now())
+	check(err)
+
+	checkContents(t, processed, "321")
+}
+
+func TestMacroFromIncludedFile(t *testing.T) {
+	files := make(map[string]mg.WebFile)
+	resolver := mg.DefaultFileResolver{BasePath: "source", Files: &mg.WebFilesMap{WebFiles: files}}
+
+	r := bufio.NewReader(strings.NewReader(
+		"{{ macro link(href, text) }}<a href=\"{{ eval href }}\">{{ eval text }}</a>{{ end }}"))
+	macros, err := mg.ProcessReader(r, "source/processed/_macros.html", "source", 0, &resolver, time.Now())
+	check(err)
+
+	r = bufio.NewReader(strings.NewReader("{{ include _macros.html }}See {{ eval link(\"/a.html\", \"A\") }}."))
+	processed, err := mg.ProcessReader(r, "source/processed/page.html", "source", 0, &resolver, time.Now())
+	check(err)
+
+	files["source/processed/_macros.html"] = mg.WebFile{Processed: macros}
+	files["source/processed/page.html"] = mg.WebFile{Processed: processed}
+
+	checkContents(t, processed, "See <a href=\"/a.html\">A</a>.")
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renatoathaydes/magnanimous/mg"
+)
+
+func TestMarkdownDefaultExtensions(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("# Hello\n\n\"Quoted\" -- text[^1].\n\n[^1]: A note."))
+	pf, err := mg.ProcessReader(r, "source/processed/doc.md", "source/processed", 0, nil, time.Now())
+	check(err)
+
+	checkContents(t, pf, "<h1>Hello</h1>\n\n<p>&ldquo;Quoted&rdquo; &ndash; text[^1].</p>\n\n<p>[^1]: A note.</p>\n")
+}
+
+func TestMarkdownExtensionsFromVariable(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader(
+		"{{ define markdownExtensions [\"autoHeadingIds\", \"footnotes\", \"-smartypants\"] }}" +
+			"# Hello\n\n\"Quoted\" -- text[^1].\n\n[^1]: A note."))
+	pf, err := mg.ProcessReader(r, "source/processed/doc.md", "source/processed", 0, nil, time.Now())
+	check(err)
+
+	checkContents(t, pf, "<h1 id=\"hello\">Hello</h1>\n\n"+
+		"<p>&quot;Quoted&quot; -- text<sup class=\"footnote-ref\" id=\"fnref:1\"><a href=\"#fn:1\">1</a></sup>.</p>\n\n"+
+		"<div class=\"footnotes\">\n\n<hr />\n\n<ol>\n"+
+		"<li id=\"fn:1\">A note. <a class=\"footnote-return\" href=\"#fnref:1\"><span aria-label='Return'>↩︎</span></a></li>\n"+
+		"</ol>\n\n</div>\n")
+}
+
+func TestMarkdownExtensionsFromString(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader(
+		"{{ define markdownExtensions \"hardLineBreaks, safe, unknown\" }}" +
+			"line 1\nline 2 <b>bold</b>\n\n[link](javascript:alert)"))
+	pf, err := mg.ProcessReader(r, "source/processed/doc.md", "source/processed", 0, nil, time.Now())
+	check(err)
+
+	checkContents(t, pf, "<p>line 1<br />\nline 2 bold</p>\n\n<p><tt>link</tt></p>\n")
+}
+
+func TestMarkdownCodeWithClasses(t *testing.T) {
+	mg.SetCodeClasses(true)
+	defer mg.SetCodeClasses(false)
+
+	r := bufio.NewReader(strings.NewReader("```go\nvar x = 1\n```"))
+	pf, err := mg.ProcessReader(r, "source/processed/doc.md", "source/processed", 0, nil, time.Now())
+	check(err)
+
+	checkContents(t, pf, `<pre tabindex="0" class="chroma"><code><span class="line"><span class="cl">`+
+		`<span class="kd">var</span> <span class="nx">x</span> <span class="p">=</span> <span class="mi">1</span>
+</span></span></code></pre>`)
+}
+
+func TestMarkdownCodeBlockOptions(t *testing.T) {
+	mg.SetCodeClasses(true)
+	defer mg.SetCodeClasses(false)
+
+	r := bufio.NewReader(strings.NewReader(
+		"```go {linenos=true hl_lines=\"4\" linenostart=3 title=\"main.go\"}\nvar x = 1\nvar y = 2\n```\n\n" +
+			"```{linenos=table}\nhi\n```"))
+	pf, err := mg.ProcessReader(r, "source/processed/doc.md", "source/processed", 0, nil, time.Now())
+	check(err)
+
+	checkContents(t, pf, `<figure class="code">
+<figcaption>main.go</figcaption>
+<pre tabindex="0" class="chroma"><code><span class="line"><span class="ln">3</span><span class="cl">`+
+		`<span class="kd">var</span> <span class="nx">x</span> <span class="p">=</span> <span class="mi">1</span>
+</span></span><span class="line hl"><span class="ln">4</span><span class="cl">`+
+		`<span class="kd">var</span> <span class="nx">y</span> <span class="p">=</span> <span class="mi">2</span>
+</span></span></code></pre></figure>
+<div class="chroma">
+<table class="lntable"><tr><td class="lntd">
+<pre tabindex="0" class="chroma"><span class="lnt">1
+</span></pre></td>
+<td class="lntd">
+<pre tabindex="0" class="chroma"><code><span class="line"><span class="cl">hi
+</span></span></code></pre></td></tr></table>
+</div>
+`)
+}
@@ -2,8 +2,11 @@ package tests
 
 import (
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/renatoathaydes/magnanimous/mg"
@@ -355,6 +358,533 @@ func TestProj7(t *testing.T) {
 	assertFileContents(t, files, dir, "other.html", "<h2>Header</h2>\n\n<p>must not evaluate this in includeRaw</p>\n")
 }
 
+func TestProj8(t *testing.T) {
+	dir := runMg(t, "test_proj_8")
+	defer os.RemoveAll(dir)
+
+	files, err := readAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 output file, but got: %v", files)
+	}
+
+	assertFileContents(t, files, dir, "index.txt", "Data Site - The Team\n"+
+		"* Alice (Lead)\n"+
+		"* Bob (Developer)\n"+
+		"docs: /docs.html\n"+
+		"home: /index.html\n"+
+		"1.1 released on 2019-06-01\n"+
+		"1.0 released on 2019-01-01\n")
+}
+
+func TestProj9(t *testing.T) {
+	check(os.Setenv("MG_TEST_ENV", "testing"))
+	defer os.Unsetenv("MG_TEST_ENV")
+
+	dir := runMg(t, "test_proj_9")
+	defer os.RemoveAll(dir)
+
+	files, err := readAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertFileContents(t, files, dir, "index.txt", "Base: /site/\n"+
+		"Docs: /site/docs\n"+
+		"Version: dev\n"+
+		"Env: testing\n"+
+		"Missing: none\n"+
+		"Profile: none")
+}
+
+func TestProj9WithDefinitions(t *testing.T) {
+	dir := runMagnanimous(t, mg.Magnanimous{SourcesDir: "test_proj_9", Definitions: map[string]string{
+		"baseURL": "/ci/",
+		"version": "1.2.3",
+	}})
+	defer os.RemoveAll(dir)
+
+	files, err := readAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertFileContents(t, files, dir, "index.txt", "Base: /ci/\n"+
+		"Docs: /ci/docs\n"+
+		"Version: 1.2.3\n"+
+		"Env: \n"+
+		"Missing: none\n"+
+		"Profile: none")
+}
+
+func TestProj9WithProfile(t *testing.T) {
+	dir := runMagnanimous(t, mg.Magnanimous{SourcesDir: "test_proj_9", Profile: "prod"})
+	defer os.RemoveAll(dir)
+
+	files, err := readAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertFileContents(t, files, dir, "index.txt", "Base: /prod/\n"+
+		"Docs: /prod/documentation\n"+
+		"Version: dev\n"+
+		"Env: \n"+
+		"Missing: none\n"+
+		"Profile: prod")
+}
+
+func TestProj9WithProfileAndDefinitions(t *testing.T) {
+	dir := runMagnanimous(t, mg.Magnanimous{SourcesDir: "test_proj_9", Profile: "prod",
+		Definitions: map[string]string{"baseURL": "/ci/"}})
+	defer os.RemoveAll(dir)
+
+	files, err := readAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertFileContents(t, files, dir, "index.txt", "Base: /ci/\n"+
+		"Docs: /ci/documentation\n"+
+		"Version: dev\n"+
+		"Env: \n"+
+		"Missing: none\n"+
+		"Profile: prod")
+}
+
+func TestProj10(t *testing.T) {
+	dir := runMg(t, "test_proj_10")
+	defer os.RemoveAll(dir)
+
+	files, err := readAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 output files, but got: %v", files)
+	}
+
+	assertFileContents(t, files, dir, "index.txt", "Published Old \nDraft contents: B")
+	assertFileContents(t, files, dir, "posts/a.txt", "A")
+	assertFileContents(t, files, dir, "posts/e.txt", "E")
+}
+
+func TestProj10WithDraftsAndFuture(t *testing.T) {
+	dir := runMagnanimous(t, mg.Magnanimous{SourcesDir: "test_proj_10", IncludeDrafts: true, IncludeFuture: true})
+	defer os.RemoveAll(dir)
+
+	files, err := readAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 5 {
+		t.Fatalf("Expected 5 output files, but got: %v", files)
+	}
+
+	assertFileContents(t, files, dir, "index.txt", "Published Draft Future Old \nDraft contents: B")
+}
+
+func TestProj10WithDraftsFromProfile(t *testing.T) {
+	dir := runMagnanimous(t, mg.Magnanimous{SourcesDir: "test_proj_10", Profile: "preview"})
+	defer os.RemoveAll(dir)
+
+	files, err := readAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 4 {
+		t.Fatalf("Expected 4 output files, but got: %v", files)
+	}
+
+	assertFileContents(t, files, dir, "index.txt", "Published Draft Old \nDraft contents: B")
+}
+
+func TestProj10WithDraftsFromDefinitions(t *testing.T) {
+	dir := runMagnanimous(t, mg.Magnanimous{SourcesDir: "test_proj_10", Definitions: map[string]string{
+		"buildDrafts": "true",
+		"buildFuture": "false",
+	}})
+	defer os.RemoveAll(dir)
+
+	files, err := readAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 4 {
+		t.Fatalf("Expected 4 output files, but got: %v", files)
+	}
+
+	assertFileContents(t, files, dir, "index.txt", "Published Draft Old \nDraft contents: B")
+}
+
+func TestProj11(t *testing.T) {
+	dir := runMg(t, "test_proj_11")
+	defer os.RemoveAll(dir)
+
+	files, err := readAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 7 {
+		t.Fatalf("Expected 7 output files, but got: %v", files)
+	}
+
+	assertFileContents(t, files, dir, "tags/index.html", "Go (3): /tags/go/index.html\n"+
+		"Web Dev (2): /tags/web-dev/index.html\n")
+	assertFileContents(t, files, dir, "tags/go/index.html", "Posts tagged Go (tags):\n"+
+		"* Channels at /posts/e.txt\n"+
+		"* Concurrency at /posts/d.txt\n"+
+		"* Go Intro at /posts/a.txt\n")
+	assertFileContents(t, files, dir, "tags/web-dev/index.html", "Posts tagged Web Dev (tags):\n"+
+		"* Go Intro at /posts/a.txt\n"+
+		"* Web Basics at /posts/b.txt\n")
+}
+
+func TestProj11WithBaseURLAndSitemap(t *testing.T) {
+	lastUpdated := time.Date(2020, 5, 17, 10, 30, 0, 0, time.UTC)
+	for i, f := range []string{"a.txt", "b.txt", "c.txt", "d.txt", "e.txt"} {
+		fileTime := lastUpdated.Add(time.Duration(i) * time.Hour)
+		check(os.Chtimes(filepath.Join("test_proj_11/processed/posts", f), fileTime, fileTime))
+	}
+
+	dir := runMagnanimous(t, mg.Magnanimous{SourcesDir: "test_proj_11", Sitemap: true,
+		Definitions: map[string]string{"baseURL": "https://example.org/blog/"}})
+	defer os.RemoveAll(dir)
+
+	files, err := readAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 8 {
+		t.Fatalf("Expected 8 output files, but got: %v", files)
+	}
+
+	assertFileContents(t, files, dir, "tags/index.html", "Go (3): https://example.org/blog/tags/go/index.html\n"+
+		"Web Dev (2): https://example.org/blog/tags/web-dev/index.html\n")
+	assertFileContents(t, files, dir, "sitemap.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://example.org/blog/posts/a.txt</loc>
+    <lastmod>2020-05-17T10:30:00Z</lastmod>
+  </url>
+  <url>
+    <loc>https://example.org/blog/posts/b.txt</loc>
+    <lastmod>2020-05-17T11:30:00Z</lastmod>
+  </url>
+  <url>
+    <loc>https://example.org/blog/posts/d.txt</loc>
+    <lastmod>2020-05-17T13:30:00Z</lastmod>
+  </url>
+  <url>
+    <loc>https://example.org/blog/posts/e.txt</loc>
+    <lastmod>2020-05-17T14:30:00Z</lastmod>
+  </url>
+  <url>
+    <loc>https://example.org/blog/tags/go/index.html</loc>
+    <lastmod>2020-05-17T14:30:00Z</lastmod>
+  </url>
+  <url>
+    <loc>https://example.org/blog/tags/index.html</loc>
+    <lastmod>2020-05-17T14:30:00Z</lastmod>
+  </url>
+  <url>
+    <loc>https://example.org/blog/tags/web-dev/index.html</loc>
+    <lastmod>2020-05-17T11:30:00Z</lastmod>
+  </url>
+</urlset>
+`)
+}
+
+func TestProj12Sitemap(t *testing.T) {
+	lastUpdated := time.Date(2020, 5, 17, 10, 30, 0, 0, time.UTC)
+	for _, f := range []string{"index.md", "docs/index.html", "docs/hidden.html", "docs/nested/deep.html"} {
+		check(os.Chtimes(filepath.Join("test_proj_12/processed", f), lastUpdated, lastUpdated))
+	}
+
+	dir := runMagnanimous(t, mg.Magnanimous{SourcesDir: "test_proj_12", Sitemap: true})
+	defer os.RemoveAll(dir)
+
+	files, err := readAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 6 {
+		t.Fatalf("Expected 6 output files, but got: %v", files)
+	}
+
+	assertFileContents(t, files, dir, "sitemap.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://example.org/site/docs/index.html</loc>
+    <lastmod>2020-05-17T10:30:00Z</lastmod>
+  </url>
+  <url>
+    <loc>https://example.org/site/docs/nested/deep.html</loc>
+    <lastmod>2020-05-17T10:30:00Z</lastmod>
+  </url>
+  <url>
+    <loc>https://example.org/site/index.html</loc>
+    <lastmod>2020-05-17T10:30:00Z</lastmod>
+  </url>
+</urlset>
+`)
+}
+
+func TestProj13Feeds(t *testing.T) {
+	feed, err := mg.ParseFeed("/posts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := runMagnanimous(t, mg.Magnanimous{SourcesDir: "test_proj_13", Feeds: []mg.Feed{feed}})
+	defer os.RemoveAll(dir)
+
+	files, err := readAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 5 {
+		t.Fatalf("Expected 5 output files, but got: %v", files)
+	}
+
+	firstContent := "&lt;h1&gt;Hello&lt;/h1&gt;&#xA;&#xA;&lt;p&gt;Some &lt;em&gt;text&lt;/em&gt; &amp;amp; more.&lt;/p&gt;&#xA;"
+
+	assertFileContents(t, files, dir, "posts/feed.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel>
+    <title>My Blog</title>
+    <link>https://example.org/posts/</link>
+    <description>Posts &amp; news</description>
+    <lastBuildDate>Thu, 06 May 2021 08:30:00 +0000</lastBuildDate>
+    <item>
+      <title>Second post</title>
+      <link>https://example.org/posts/second.html</link>
+      <guid>https://example.org/posts/second.html</guid>
+      <pubDate>Thu, 06 May 2021 08:30:00 +0000</pubDate>
+      <description>The second one</description>
+      <content:encoded>&lt;p&gt;Second&lt;/p&gt;</content:encoded>
+    </item>
+    <item>
+      <title>First &lt;post&gt;</title>
+      <link>https://example.org/posts/first.html</link>
+      <guid>https://example.org/posts/first.html</guid>
+      <pubDate>Thu, 04 Mar 2021 10:00:00 +0000</pubDate>
+      <dc:creator>Joe</dc:creator>
+      <description>&lt;p&gt;Some &lt;em&gt;text&lt;/em&gt; &amp;amp; more.&lt;/p&gt;</description>
+      <content:encoded>`+firstContent+`</content:encoded>
+    </item>
+  </channel>
+</rss>
+`)
+
+	assertFileContents(t, files, dir, "posts/atom.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>My Blog</title>
+  <id>https://example.org/posts/</id>
+  <link href="https://example.org/posts/"></link>
+  <link href="https://example.org/posts/atom.xml" rel="self"></link>
+  <updated>2021-05-06T08:30:00Z</updated>
+  <entry>
+    <title>Second post</title>
+    <id>https://example.org/posts/second.html</id>
+    <link href="https://example.org/posts/second.html"></link>
+    <updated>2021-05-06T08:30:00Z</updated>
+    <summary type="html">The second one</summary>
+    <content type="html">&lt;p&gt;Second&lt;/p&gt;</content>
+  </entry>
+  <entry>
+    <title>First &lt;post&gt;</title>
+    <id>https://example.org/posts/first.html</id>
+    <link href="https://example.org/posts/first.html"></link>
+    <updated>2021-03-04T10:00:00Z</updated>
+    <author>
+      <name>Joe</name>
+    </author>
+    <summary type="html">&lt;p&gt;Some &lt;em&gt;text&lt;/em&gt; &amp;amp; more.&lt;/p&gt;</summary>
+    <content type="html">`+firstContent+`</content>
+  </entry>
+</feed>
+`)
+}
+
+func TestProj13RSSFeedOnly(t *testing.T) {
+	feed, err := mg.ParseFeed("/posts:rss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := runMagnanimous(t, mg.Magnanimous{SourcesDir: "test_proj_13", Feeds: []mg.Feed{feed}})
+	defer os.RemoveAll(dir)
+
+	files, err := readAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 4 {
+		t.Fatalf("Expected 4 output files, but got: %v", files)
+	}
+	if _, err = mg.ParseFeed("/posts:json"); err == nil {
+		t.Error("Expected error parsing feed with unknown format")
+	}
+}
+
+func TestFeedEntriesUseOwnContextAndSkipIndex(t *testing.T) {
+	source := fstest.MapFS{
+		"processed/_global_context": {Data: []byte(`{{ define siteURL "https://example.org" }}` +
+			`{{ define title "My Blog" }}{{ define author "Site Author" }}`)},
+		"processed/posts/index.html":    {Data: []byte("<p>All posts</p>")},
+		"processed/posts/untitled.html": {Data: []byte("<p>No title</p>")},
+	}
+	mag := mg.Magnanimous{SourcesDir: ".", Source: source, Feeds: []mg.Feed{{Dir: "posts", RSS: true}}}
+	webFiles, err := mag.ReadAll()
+	check(err)
+
+	out := mg.MemoryOutput{}
+	check(mag.WriteOutput(out, webFiles))
+
+	feed := string(out["posts/feed.xml"])
+	if strings.Count(feed, "<item>") != 1 || strings.Contains(feed, "posts/index.html") {
+		t.Errorf("Expected the index file not to be a feed entry, but got:\n%s", feed)
+	}
+	if !strings.Contains(feed, "<title>untitled.html</title>") || strings.Contains(feed, "Site Author") {
+		t.Errorf("Expected the feed entry to use the file's own context only, but got:\n%s", feed)
+	}
+}
+
+func TestProj13CodeStylesheets(t *testing.T) {
+	dir := runMagnanimous(t, mg.Magnanimous{SourcesDir: "test_proj_13", CodeStylesheets: []string{"monokai", "github"}})
+	defer os.RemoveAll(dir)
+
+	for style, rule := range map[string]string{
+		"monokai": "\n/* KeywordDeclaration */ .chroma .kd {",
+		"github":  "\n/* KeywordDeclaration */ .chroma-github .chroma .kd {",
+	} {
+		css, err := os.ReadFile(filepath.Join(dir, "chroma-"+style+".css"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(css), rule) {
+			t.Errorf("Unexpected %s stylesheet:\n%s", style, css)
+		}
+	}
+	github, err := os.ReadFile(filepath.Join(dir, "chroma-github.css"))
+	check(err)
+	if strings.Contains(string(github), "*/ .chroma ") ||
+		!strings.HasPrefix(string(github), "/* Background */ .chroma-github .bg {") {
+		t.Errorf("Expected all rules of the github stylesheet to be scoped:\n%s", github)
+	}
+
+	mag := mg.Magnanimous{SourcesDir: "test_proj_13", CodeStylesheets: []string{"no-such-style"}}
+	webFiles, err := mag.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err = os.MkdirTemp("", "test_proj_13")
+	check(err)
+	defer os.RemoveAll(dir)
+	shouldHaveError(t, mag.WriteTo(dir, webFiles), "unknown code style: no-such-style")
+}
+
+func TestProj14MarkdownLinks(t *testing.T) {
+	dir := runMagnanimous(t, mg.Magnanimous{SourcesDir: "test_proj_14"})
+	defer os.RemoveAll(dir)
+
+	files, err := readAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 output files, but got: %v", files)
+	}
+
+	assertFileContents(t, files, dir, "index.html", `<h1>Home</h1>
+
+<p>See <a href="about.html">about</a>, the <a href="/docs/guide.html#install">guide</a>, <a href="missing.md">nothing</a>
+and <a href="https://example.org/page.md">elsewhere</a>.</p>
+`)
+	assertFileContents(t, files, dir, "docs/guide.html", `<h2>Install</h2>
+
+<p>Go <a href="../index.html?from=guide">back</a>.</p>
+`)
+}
+
+func TestProj15DraftsFromStringsAndOwnScope(t *testing.T) {
+	dir := runMg(t, "test_proj_15")
+	defer os.RemoveAll(dir)
+
+	files, err := readAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 output files, but got: %v", files)
+	}
+
+	assertFileContents(t, files, dir, "index.txt", "Published Not Draft ")
+}
+
+func TestProj16CheckLinks(t *testing.T) {
+	checkLinks := func(mag mg.Magnanimous, out mg.Output) []string {
+		webFiles, err := mag.ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = mag.WriteOutput(out, webFiles); err != nil {
+			t.Fatal(err)
+		}
+		broken, err := mag.FindBrokenLinks(out, webFiles)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result []string
+		for _, b := range broken {
+			result = append(result, b.String())
+		}
+		return result
+	}
+
+	index := filepath.Join("test_proj_16", "processed", "index.md")
+	links := filepath.Join("test_proj_16", "processed", "links.html")
+	hello := filepath.Join("test_proj_16", "processed", "posts", "hello.md")
+	tag := filepath.Join("test_proj_16", "processed", "_taxonomy_tag.html")
+	posts := filepath.Join("test_proj_16", "processed", "posts")
+	mag := mg.Magnanimous{SourcesDir: "test_proj_16", Feeds: []mg.Feed{{Dir: "posts", RSS: true, Atom: true}}}
+
+	dir, err := os.MkdirTemp("", "test_proj_16")
+	check(err)
+	defer os.RemoveAll(dir)
+
+	for _, out := range []mg.Output{mg.DirOutput(dir), mg.MemoryOutput{}} {
+		assertBrokenLinks(t, checkLinks(mag, out), []string{
+			"(" + tag + ":3:42) broken link /tags/: target does not exist",
+			"(" + index + ":3:71) broken link missing.md: target does not exist",
+			"(" + links + ":2:10) broken link #bottom: anchor does not exist",
+			"(" + links + ":4:43) broken link /logo.png: target does not exist",
+			"(" + hello + ":5:24) broken link ../docs/guide.html#usage: anchor does not exist",
+			"(" + posts + ":0:0) broken link https://site.example/posts/: target does not exist",
+			"(" + posts + ":0:0) broken link https://site.example/posts/: target does not exist",
+		})
+	}
+
+	assertBrokenLinks(t, checkLinks(mg.Magnanimous{SourcesDir: "test_proj_16",
+		Definitions: map[string]string{"baseURL": "https://example.org/ci/"}}, mg.MemoryOutput{}), []string{
+		"(" + tag + ":0:0) broken link /posts/hello.html: absolute link does not start with the baseURL (/ci/)",
+		"(" + tag + ":3:42) broken link /tags/: absolute link does not start with the baseURL (/ci/)",
+		"(" + index + ":3:36) broken link /docs/guide.html#install: absolute link does not start with the baseURL (/ci/)",
+		"(" + index + ":3:71) broken link missing.md: target does not exist",
+		"(" + links + ":2:10) broken link #bottom: anchor does not exist",
+		"(" + links + ":4:10) broken link /about.html: absolute link does not start with the baseURL (/ci/)",
+		"(" + links + ":4:43) broken link /logo.png: absolute link does not start with the baseURL (/ci/)",
+		"(" + hello + ":5:24) broken link ../docs/guide.html#usage: anchor does not exist",
+	})
+}
+
+func assertBrokenLinks(t *testing.T, actual, expected []string) {
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected broken links:\n%s\nbut got:\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
+
 // Initial results:
 // 789098 ns/op
 // 801477 ns/op
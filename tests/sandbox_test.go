@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/renatoathaydes/magnanimous/mg"
+)
+
+// createSandboxProject creates a project with the given processed files and a secret file outside of its
+// sources directory, returning the root directory containing both.
+func createSandboxProject(t *testing.T, files map[string]string) string {
+	root, err := ioutil.TempDir("", "sandbox_test")
+	check(err)
+	check(os.MkdirAll(filepath.Join(root, "source", "processed"), 0755))
+	check(ioutil.WriteFile(filepath.Join(root, "secret.txt"), []byte("secret"), 0644))
+	for name, contents := range files {
+		check(ioutil.WriteFile(filepath.Join(root, "source", "processed", name), []byte(contents), 0644))
+	}
+	return root
+}
+
+func buildSandboxProject(root string, sandbox bool) (string, error) {
+	mag := mg.Magnanimous{SourcesDir: filepath.Join(root, "source"), Sandbox: sandbox}
+	webFiles, err := mag.ReadAll()
+	if err != nil {
+		return "", err
+	}
+	target := filepath.Join(root, "target")
+	if err = mag.WriteTo(target, webFiles); err != nil {
+		return "", err
+	}
+	contents, err := ioutil.ReadFile(filepath.Join(target, "index.txt"))
+	return string(contents), err
+}
+
+func TestSandboxRejectsPathsOutsideSources(t *testing.T) {
+	for _, path := range []string{"../../secret.txt", "/../secret.txt", "eval \"../\" + \"../secret.txt\""} {
+		root := createSandboxProject(t, map[string]string{"index.txt": "{{ includeRaw " + path + " }}"})
+		defer os.RemoveAll(root)
+
+		_, err := buildSandboxProject(root, true)
+
+		if err == nil || !strings.Contains(err.Error(), "outside of the sources directory") {
+			t.Errorf("Expected error including path %s, got: %v", path, err)
+		}
+	}
+}
+
+func TestSandboxAllowsPathsWithinSources(t *testing.T) {
+	root := createSandboxProject(t, map[string]string{
+		"index.txt":  "{{ include ../processed/_other.txt }} {{ include /processed/_other.txt }}",
+		"_other.txt": "other",
+	})
+	defer os.RemoveAll(root)
+
+	contents, err := buildSandboxProject(root, true)
+	check(err)
+
+	verifyEqual(1, t, contents, "other other")
+}
+
+func TestSandboxRejectsSymlinksOutsideSources(t *testing.T) {
+	root := createSandboxProject(t, map[string]string{"index.txt": "{{ include _secret.txt }}"})
+	defer os.RemoveAll(root)
+	link := filepath.Join(root, "source", "processed", "_secret.txt")
+	if err := os.Symlink(filepath.Join(root, "secret.txt"), link); err != nil {
+		t.Skipf("cannot create symbolic link: %v", err)
+	}
+
+	contents, err := buildSandboxProject(root, false)
+	check(err)
+	verifyEqual(1, t, contents, "secret")
+
+	_, err = buildSandboxProject(root, true)
+
+	if err == nil || !strings.Contains(err.Error(), "_secret.txt resolves to") ||
+		!strings.Contains(err.Error(), "outside of the sources directory") {
+		t.Errorf("Expected error due to symbolic link, got: %v", err)
+	}
+}
+
+func TestSandboxHidesEnvironmentVariables(t *testing.T) {
+	t.Setenv("MAGNANIMOUS_SECRET", "secret")
+	root := createSandboxProject(t, map[string]string{"index.txt": "{{ eval env[\"MAGNANIMOUS_SECRET\"] }}"})
+	defer os.RemoveAll(root)
+
+	contents, err := buildSandboxProject(root, false)
+	check(err)
+	verifyEqual(1, t, contents, "secret")
+
+	contents, err = buildSandboxProject(root, true)
+	check(err)
+	verifyEqual(2, t, contents, "{{ eval env[\"MAGNANIMOUS_SECRET\"] }}")
+}
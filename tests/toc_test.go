@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renatoathaydes/magnanimous/mg"
+)
+
+func TestTocInMarkdown(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("# Title\n\n{{ toc }}\n\n## Intro & more\n\nText\n\n" +
+		"### Details\n\n## Intro & more\n\n<h2 id=\"custom\">Custom</h2>\n"))
+	pf, err := mg.ProcessReader(r, "source/processed/doc.md", "source/processed", 0, nil, time.Now())
+	check(err)
+
+	checkContents(t, pf, `<h1 id="title">Title</h1>
+
+<ul class="toc">
+<li><a href="#title">Title</a>
+<ul>
+<li><a href="#intro-more">Intro &amp; more</a>
+<ul>
+<li><a href="#details">Details</a></li>
+</ul>
+</li>
+<li><a href="#intro-more-1">Intro &amp; more</a></li>
+<li><a href="#custom">Custom</a></li>
+</ul>
+</li>
+</ul>
+
+<h2 id="intro-more">Intro &amp; more</h2>
+
+<p>Text</p>
+
+<h3 id="details">Details</h3>
+
+<h2 id="intro-more-1">Intro &amp; more</h2>
+
+<h2 id="custom">Custom</h2>
+`)
+}
+
+func TestTocWithMaxLevel(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("<nav>{{ toc 2 }}</nav>\n" +
+		"<h2 class=\"big\">First <em>one</em></h2>\n<h3>Hidden</h3>\n<h2>Second</h2>"))
+	pf, err := mg.ProcessReader(r, "source/processed/doc.html", "source/processed", 0, nil, time.Now())
+	check(err)
+
+	checkContents(t, pf, `<nav><ul class="toc">
+<li><a href="#first-one">First one</a></li>
+<li><a href="#second">Second</a></li>
+</ul></nav>
+<h2 class="big" id="first-one">First <em>one</em></h2>
+<h3 id="hidden">Hidden</h3>
+<h2 id="second">Second</h2>`)
+}
+
+func TestTocInSafeMarkdown(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("{{ define markdownExtensions \"safe\" }}" +
+		"# Title\n\n{{ toc }}\n\n<div>skipped</div>\n\n## Intro <b>skipped</b>\n\nSee: {{ toc 1 }}\n"))
+	pf, err := mg.ProcessReader(r, "source/processed/doc.md", "source/processed", 0, nil, time.Now())
+	check(err)
+
+	checkContents(t, pf, `<h1 id="title">Title</h1>
+
+<ul class="toc">
+<li><a href="#title">Title</a>
+<ul>
+<li><a href="#intro-skipped">Intro skipped</a></li>
+</ul>
+</li>
+</ul>
+
+<h2 id="intro-skipped">Intro skipped</h2>
+
+<p>See: <ul class="toc">
+<li><a href="#title">Title</a></li>
+</ul></p>
+`)
+}